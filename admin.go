@@ -0,0 +1,199 @@
+package bps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	adminReg   = map[string]AdminFactory{}
+	adminRegMu sync.Mutex
+)
+
+// Topic management errors.
+var (
+	ErrTopicExists   = errors.New("bps: topic already exists")
+	ErrTopicNotFound = errors.New("bps: topic not found")
+)
+
+// Admin defines an optional topic management interface.
+type Admin interface {
+	// CreateTopic creates a topic, returns ErrTopicExists if the topic already exists.
+	// Config is optional, unsupported settings are ignored.
+	CreateTopic(ctx context.Context, name string, config *TopicConfig) error
+	// DeleteTopic deletes a topic, returns ErrTopicNotFound if the topic does not exist.
+	DeleteTopic(ctx context.Context, name string) error
+	// ListTopics returns sorted topic names.
+	ListTopics(ctx context.Context) ([]string, error)
+	// DescribeTopic returns topic details, returns ErrTopicNotFound if the topic does not exist.
+	DescribeTopic(ctx context.Context, name string) (*TopicInfo, error)
+	// Close closes the admin connection.
+	Close() error
+}
+
+// TopicConfig contains topic settings.
+// Zero values mean implementation-specific defaults.
+type TopicConfig struct {
+	// Partitions is the number of partitions.
+	// May not be supported by some implementations.
+	Partitions int
+	// ReplicationFactor is the number of replicas of each partition.
+	// May not be supported by some implementations.
+	ReplicationFactor int
+	// Retention is the minimum duration to retain messages for.
+	// May not be supported by some implementations.
+	Retention time.Duration
+}
+
+// TopicInfo contains topic details.
+type TopicInfo struct {
+	// Name is the topic name.
+	Name string
+	// TopicConfig contains the effective topic settings, unsupported ones are zero.
+	TopicConfig
+}
+
+// NewAdmin inits an admin via URL.
+//
+//   admin, err := bps.NewAdmin(context.TODO(), "kafka://10.0.0.1:9092,10.0.0.2:9092,10.0.0.3:9092")
+func NewAdmin(ctx context.Context, urlStr string) (Admin, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	adminRegMu.Lock()
+	factory, ok := adminReg[u.Scheme]
+	adminRegMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown URL scheme %q", u.Scheme)
+	}
+	return factory(ctx, u)
+}
+
+// --------------------------------------------------------------------
+
+// AdminFactory constructs an admin from a URL.
+type AdminFactory func(context.Context, *url.URL) (Admin, error)
+
+// RegisterAdmin registers a new protocol with a scheme and a corresponding
+// AdminFactory.
+func RegisterAdmin(scheme string, factory AdminFactory) {
+	adminRegMu.Lock()
+	defer adminRegMu.Unlock()
+
+	if _, exists := adminReg[scheme]; exists {
+		panic("protocol " + scheme + " already registered")
+	}
+	adminReg[scheme] = factory
+}
+
+// --------------------------------------------------------------------
+
+// CreateTopic implements Admin.
+func (p *InMemPublisher) CreateTopic(_ context.Context, name string, _ *TopicConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.topics[name]; ok {
+		return ErrTopicExists
+	}
+	p.topics[name] = new(InMemPubTopic)
+	return nil
+}
+
+// DeleteTopic implements Admin.
+func (p *InMemPublisher) DeleteTopic(_ context.Context, name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.topics[name]; !ok {
+		return ErrTopicNotFound
+	}
+	delete(p.topics, name)
+	return nil
+}
+
+// ListTopics implements Admin.
+func (p *InMemPublisher) ListTopics(_ context.Context) ([]string, error) {
+	p.mu.RLock()
+	names := make([]string, 0, len(p.topics))
+	for name := range p.topics {
+		names = append(names, name)
+	}
+	p.mu.RUnlock()
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// DescribeTopic implements Admin.
+func (p *InMemPublisher) DescribeTopic(_ context.Context, name string) (*TopicInfo, error) {
+	p.mu.RLock()
+	_, ok := p.topics[name]
+	p.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrTopicNotFound
+	}
+	return &TopicInfo{Name: name, TopicConfig: TopicConfig{Partitions: 1}}, nil
+}
+
+// --------------------------------------------------------------------
+
+// CreateTopic implements Admin.
+func (s *InMemSubscriber) CreateTopic(_ context.Context, name string, _ *TopicConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.msgs[name]; ok {
+		return ErrTopicExists
+	}
+	if s.msgs == nil {
+		s.msgs = make(map[string][]SubMessage)
+	}
+	s.msgs[name] = nil
+	return nil
+}
+
+// DeleteTopic implements Admin.
+func (s *InMemSubscriber) DeleteTopic(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.msgs[name]; !ok {
+		return ErrTopicNotFound
+	}
+	delete(s.msgs, name)
+	return nil
+}
+
+// ListTopics implements Admin.
+func (s *InMemSubscriber) ListTopics(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.msgs))
+	for name := range s.msgs {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// DescribeTopic implements Admin.
+func (s *InMemSubscriber) DescribeTopic(_ context.Context, name string) (*TopicInfo, error) {
+	s.mu.Lock()
+	_, ok := s.msgs[name]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, ErrTopicNotFound
+	}
+	return &TopicInfo{Name: name, TopicConfig: TopicConfig{Partitions: 1}}, nil
+}
@@ -0,0 +1,39 @@
+package bps_test
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/bsm/bps"
+
+	. "github.com/bsm/ginkgo"
+	. "github.com/bsm/gomega"
+)
+
+func init() {
+	bps.RegisterAdmin("mem", func(_ context.Context, u *url.URL) (bps.Admin, error) {
+		return bps.NewInMemPublisher(), nil
+	})
+}
+
+var _ = Describe("RegisterAdmin", func() {
+	var ctx = context.Background()
+
+	It("should panic when trying to register the same scheme 2x", func() {
+		Expect(func() {
+			bps.RegisterAdmin("mem", nil)
+		}).To(Panic())
+	})
+
+	It("should allow to init admins by URL", func() {
+		admin, err := bps.NewAdmin(ctx, "mem://test.host/path")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(admin).To(BeAssignableToTypeOf(&bps.InMemPublisher{}))
+		Expect(admin.Close()).To(Succeed())
+	})
+
+	It("should fail on unknown schemes", func() {
+		_, err := bps.NewAdmin(ctx, "unknown://test.host/path")
+		Expect(err).To(MatchError(`unknown URL scheme "unknown"`))
+	})
+})
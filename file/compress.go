@@ -0,0 +1,284 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression defines the compression of topic files.
+type Compression string
+
+// Compression options.
+const (
+	// CompressionNone stores topic files uncompressed.
+	CompressionNone Compression = "none"
+
+	// CompressionGzip stores topic files as concatenated gzip members.
+	CompressionGzip Compression = "gzip"
+
+	// CompressionZstd stores topic files as concatenated zstd frames.
+	CompressionZstd Compression = "zstd"
+)
+
+var compressions = []string{string(CompressionNone), string(CompressionGzip), string(CompressionZstd)}
+
+// compressionFor infers topic compression from the topic name extension.
+func compressionFor(name string) Compression {
+	switch filepath.Ext(name) {
+	case ".gz":
+		return CompressionGzip
+	case ".zst":
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// sniffCompression detects compression by the file header.
+// It returns false, if head is too short to decide.
+func sniffCompression(head []byte) (Compression, bool) {
+	for _, c := range []struct {
+		compression Compression
+		magic       []byte
+	}{
+		{CompressionGzip, gzipMagic},
+		{CompressionZstd, zstdMagic},
+	} {
+		n := len(head)
+		if n > len(c.magic) {
+			n = len(c.magic)
+		}
+		if bytes.Equal(head[:n], c.magic[:n]) {
+			return c.compression, n == len(c.magic)
+		}
+	}
+	return CompressionNone, true
+}
+
+// --------------------------------------------------------------------
+
+// compressor compresses batches of records.
+type compressor interface {
+	// Compress appends a complete, self-contained compressed frame of src to dst.
+	Compress(dst, src []byte) ([]byte, error)
+	// Close releases resources.
+	Close() error
+}
+
+// newCompressor returns a compressor, it returns nil for CompressionNone.
+func newCompressor(compression Compression) (compressor, error) {
+	switch compression {
+	case CompressionNone, "":
+		return nil, nil
+	case CompressionGzip:
+		return new(gzipCompressor), nil
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdCompressor{enc: enc}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// decompressor decompresses frames.
+type decompressor interface {
+	// Decompress decompresses the first frame of data and appends its content to dst.
+	// It returns 0 advance, if data contains no complete frame.
+	Decompress(dst, data []byte) (advance int, out []byte, err error)
+	// Close releases resources.
+	Close() error
+}
+
+// newDecompressor returns a decompressor, it returns nil for CompressionNone.
+func newDecompressor(compression Compression) (decompressor, error) {
+	switch compression {
+	case CompressionNone, "":
+		return nil, nil
+	case CompressionGzip:
+		return new(gzipDecompressor), nil
+	case CompressionZstd:
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdDecompressor{dec: dec}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// --------------------------------------------------------------------
+
+type gzipCompressor struct {
+	w *gzip.Writer
+}
+
+func (c *gzipCompressor) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	if c.w == nil {
+		c.w = gzip.NewWriter(buf)
+	} else {
+		c.w.Reset(buf)
+	}
+
+	if _, err := c.w.Write(src); err != nil {
+		return dst, err
+	}
+	if err := c.w.Close(); err != nil {
+		return dst, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (*gzipCompressor) Close() error { return nil }
+
+type gzipDecompressor struct {
+	r  gzip.Reader
+	br bytes.Reader
+}
+
+func (d *gzipDecompressor) Decompress(dst, data []byte) (int, []byte, error) {
+	// bytes.Reader is an io.ByteReader, so gzip.Reader does not read beyond the member end:
+	d.br.Reset(data)
+	if err := d.r.Reset(&d.br); err != nil {
+		return incompleteFrame(dst, err)
+	}
+	d.r.Multistream(false)
+
+	buf := bytes.NewBuffer(dst)
+	if _, err := buf.ReadFrom(&d.r); err != nil {
+		return incompleteFrame(dst, err)
+	}
+	return len(data) - d.br.Len(), buf.Bytes(), nil
+}
+
+func (*gzipDecompressor) Close() error { return nil }
+
+// incompleteFrame treats unexpected EOF errors as incomplete frames.
+func incompleteFrame(dst []byte, err error) (int, []byte, error) {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, dst, nil
+	}
+	return 0, dst, err
+}
+
+// --------------------------------------------------------------------
+
+type zstdCompressor struct {
+	enc *zstd.Encoder
+}
+
+func (c *zstdCompressor) Compress(dst, src []byte) ([]byte, error) {
+	return c.enc.EncodeAll(src, dst), nil
+}
+
+func (c *zstdCompressor) Close() error {
+	return c.enc.Close()
+}
+
+type zstdDecompressor struct {
+	dec *zstd.Decoder
+}
+
+func (d *zstdDecompressor) Decompress(dst, data []byte) (int, []byte, error) {
+	n, err := zstdFrameSize(data)
+	if err != nil || n == 0 {
+		return 0, dst, err
+	}
+
+	out, err := d.dec.DecodeAll(data[:n], dst)
+	if err != nil {
+		return 0, dst, err
+	}
+	return n, out, nil
+}
+
+func (d *zstdDecompressor) Close() error {
+	d.dec.Close()
+	return nil
+}
+
+var errInvalidZstdFrame = errors.New("invalid zstd frame")
+
+// zstdFrameSize returns the size of the first zstd frame in data, or 0 if data contains no complete frame.
+// It walks frame and block headers, as described in RFC 8878.
+func zstdFrameSize(data []byte) (int, error) {
+	if len(data) < 4 {
+		return 0, nil
+	}
+
+	magic := binary.LittleEndian.Uint32(data)
+	if magic&0xfffffff0 == 0x184d2a50 { // skippable frame
+		if len(data) < 8 {
+			return 0, nil
+		}
+		if n := 8 + int64(binary.LittleEndian.Uint32(data[4:])); int64(len(data)) >= n {
+			return int(n), nil
+		}
+		return 0, nil
+	} else if magic != 0xfd2fb528 {
+		return 0, errInvalidZstdFrame
+	}
+
+	if len(data) < 5 {
+		return 0, nil
+	}
+	desc := data[4]
+	singleSegment := desc&0x20 != 0
+
+	pos := 5
+	if !singleSegment {
+		pos++ // window descriptor
+	}
+	pos += [4]int{0, 1, 2, 4}[desc&0x3] // dictionary ID
+	switch desc >> 6 {                  // frame content size
+	case 0:
+		if singleSegment {
+			pos++
+		}
+	case 1:
+		pos += 2
+	case 2:
+		pos += 4
+	case 3:
+		pos += 8
+	}
+
+	for last := false; !last; {
+		if len(data) < pos+3 {
+			return 0, nil
+		}
+		header := uint32(data[pos]) | uint32(data[pos+1])<<8 | uint32(data[pos+2])<<16
+		pos += 3
+
+		last = header&1 != 0
+		switch (header >> 1) & 0x3 {
+		case 1: // RLE block
+			pos++
+		case 3: // reserved
+			return 0, errInvalidZstdFrame
+		default: // raw and compressed blocks
+			pos += int(header >> 3)
+		}
+	}
+
+	if desc&0x4 != 0 {
+		pos += 4 // content checksum
+	}
+	if len(data) < pos {
+		return 0, nil
+	}
+	return pos, nil
+}
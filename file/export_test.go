@@ -0,0 +1,20 @@
+package file
+
+import (
+	"os"
+
+	"github.com/bsm/bps"
+)
+
+// QueryParams declares URL query parameters, documented in the package doc.
+var QueryParams = []func(*bps.QueryParams){
+	new(PublisherConfig).params,
+	new(SubscriberConfig).params,
+}
+
+// SetFsync replaces the function, that syncs files to disk, returns a function to restore it.
+func SetFsync(fn func(*os.File) error) (restore func()) {
+	prev := fsync
+	fsync = fn
+	return func() { fsync = prev }
+}
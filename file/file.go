@@ -1,14 +1,103 @@
 // Package file implements a file-system producer.
+//
+// URL host and path define the root directory, each topic is stored as a separate file in it
+// (bps.NewAdmin manages these files):
+//
+//   file:///var/lib/bps
+//
+// bps.NewPublisher supports:
+//
+//   segment_bytes
+//     Max size of a topic segment file, before it is rolled (default unlimited).
+//   segment_age
+//     Max age of a topic segment file, before it is rolled (default unlimited).
+//   retention_bytes
+//     Max total size of rolled segments, oldest are removed first (default unlimited).
+//   retention_age
+//     Max age (since the last write) of rolled segments (default unlimited).
+//   archive_dir
+//     Directory to move segments to, instead of deleting them (default none).
+//   fsync
+//     When to sync messages to disk. Valid values are: always (default, after every message),
+//     interval (periodically, publishes wait for the next sync), group (after every message, concurrent
+//     publishes share a sync).
+//   fsync_interval
+//     Sync interval for fsync=interval (default 100ms).
+//   format
+//     On-disk message format. Valid values are: jsonl (default, JSON lines), raw (newline-delimited
+//     message data only), binary (length-prefixed records), csv (ID, data and attribute key-value pairs,
+//     without CRLF line breaks).
+//   compression
+//     Topic file compression. Valid values are: none, gzip, zstd
+//     (default inferred from the topic name extension: .gz for gzip, .zst for zstd, none otherwise).
+//   partitions
+//     Number of partition files per topic, stored within a topic directory, messages are
+//     assigned by the hash of their IDs (default 0, a single topic file).
+//
+// Compressed topics are written as concatenated gzip members or zstd frames, that can be read by standard tools.
+// Messages are compressed in batches, a batch is written when messages are synced, so fsync=interval
+// yields better compression ratios with concurrent publishes. Pending messages are written on Close.
+// Subscribers detect compression automatically, offsets of compressed topics refer to batches.
+//
+// Rolled segments are moved to the .segments sub-directory of the root directory,
+// retention limits are applied when segments are rolled. Subscribers read across segments.
+//
+// Publishers hold an exclusive advisory lock (flock) on the active segment while appending to it,
+// so several processes on one host can publish to the same topic: records are never interleaved and
+// segments are rolled by one process at a time. The order of messages from different processes is undefined.
+// Locks are advisory, they do not guard against writers, that do not use this package, and they may not
+// work on network file systems. Locking is only supported on Linux, macOS and BSDs.
+//
+// bps.NewSubscriber supports:
+//
+//   follow
+//     Keep reading topic files as they are appended to (default false).
+//   poll_interval
+//     Interval to check topic files for new messages in follow mode (default 100ms).
+//   consumer
+//     Consumer name to store and resume offsets of handled messages (default none). Delivery is
+//     at-least-once: messages, handled since the last stored offset, are re-delivered after a crash, compressed
+//     topics re-deliver the rest of a batch too.
+//   format
+//     On-disk message format. Valid values are: jsonl (default, JSON lines), raw (newline-delimited
+//     message data only), binary (length-prefixed records), csv (ID, data and attribute key-value pairs,
+//     without CRLF line breaks).
+//   consume_partitions
+//     Comma-separated partitions to consume (default all).
+//
+// Subscriptions handle *SubMessage messages, which expose message IDs and attributes.
+//
+// Named consumers store offsets in checkpoint files within the .checkpoints sub-directory
+// of the root directory. Checkpoints are not synced to disk, unreadable checkpoints (e.g. torn by
+// a crash) are ignored and consumers start over as if they were new.
+//
+// Partitioned topics are stored as <topic>/<partition> files, messages with the same ID are
+// published to the same partition, messages without IDs are distributed round-robin. Publishers create
+// the topic directory, its .partitions metadata file and all partition files on the first publish,
+// they fail, if the topic exists with a different number of partitions. Subscribers read the number
+// of partitions from the topic and consume partitions in parallel, preserving the order of messages
+// within a partition. Partitions are handled sequentially by the handler, passed to Subscribe, unless
+// bps.WithHandlerFactory is used. Each partition is segmented, compressed, locked and checkpointed
+// individually within the topic directory, bps.NewAdmin manages partitioned topics as a whole.
+//
+// Invalid or unknown query parameters are reported as errors.
+//
 package file
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bsm/bps"
 	"github.com/bsm/bps/internal/concurrent"
@@ -16,28 +105,80 @@ import (
 
 func init() {
 	bps.RegisterPublisher("file", func(_ context.Context, u *url.URL) (bps.Publisher, error) {
-		return NewPublisher(path.Join(u.Host, u.Path))
+		var config PublisherConfig
+		var params bps.QueryParams
+		config.params(&params)
+		if err := params.Parse(u.Query()); err != nil {
+			return nil, err
+		}
+		return NewPublisherWithConfig(path.Join(u.Host, u.Path), &config)
 	})
 	bps.RegisterSubscriber("file", func(_ context.Context, u *url.URL) (bps.Subscriber, error) {
-		return NewSubscriber(path.Join(u.Host, u.Path)), nil
+		var config SubscriberConfig
+		var params bps.QueryParams
+		config.params(&params)
+		if err := params.Parse(u.Query()); err != nil {
+			return nil, err
+		}
+		return NewSubscriberWithConfig(path.Join(u.Host, u.Path), &config), nil
+	})
+	bps.RegisterAdmin("file", func(_ context.Context, u *url.URL) (bps.Admin, error) {
+		var params bps.QueryParams
+		if err := params.Parse(u.Query()); err != nil {
+			return nil, err
+		}
+		return NewAdmin(path.Join(u.Host, u.Path))
 	})
 }
 
 // --------------------------------------------------------------------
 
 type filePub struct {
-	root string
+	root   string
+	config *PublisherConfig
+	codec  codec
+	syncer *concurrent.Group
 
-	topics map[string]*fileTopic
+	topics map[string]pubTopic
 	mu     sync.RWMutex
 }
 
-// NewPublisher inits a publisher within a root directory.
+// NewPublisher inits a publisher within a root directory with default settings,
+// each topic is appended to a single file.
 func NewPublisher(root string) (bps.Publisher, error) {
+	return NewPublisherWithConfig(root, nil)
+}
+
+// NewPublisherWithConfig inits a publisher within a root directory.
+// Unless segment limits are configured, each topic is appended to a single file.
+// Topics can be shared with publishers in other processes.
+// Config is optional.
+func NewPublisherWithConfig(root string, config *PublisherConfig) (bps.Publisher, error) {
+	config = config.norm()
+	codec, err := newCodec(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unknown compression %q", config.Compression)
+	}
+	if config.Partitions < 0 {
+		return nil, fmt.Errorf("invalid number of partitions: %d", config.Partitions)
+	}
+
 	if err := os.MkdirAll(root, 0777); err != nil {
 		return nil, err
 	}
-	return &filePub{root: root, topics: make(map[string]*fileTopic)}, nil
+
+	p := &filePub{root: root, config: config, codec: codec, topics: make(map[string]pubTopic)}
+	if p.config.Fsync == FsyncInterval {
+		p.syncer = concurrent.NewGroup(context.Background())
+		p.syncer.Go(p.syncLoop)
+	}
+	return p, nil
 }
 
 func (p *filePub) Topic(name string) bps.PubTopic {
@@ -53,13 +194,35 @@ func (p *filePub) Topic(name string) bps.PubTopic {
 	defer p.mu.Unlock()
 
 	if topic, ok = p.topics[name]; !ok {
-		topic = &fileTopic{name: filepath.Join(p.root, name)}
+		topic = p.newTopic(name)
 		p.topics[name] = topic
 	}
 	return topic
 }
 
+func (p *filePub) newTopic(name string) pubTopic {
+	files := topicFiles{root: p.root, name: name}
+	compression := p.config.Compression
+	if compression == "" {
+		compression = compressionFor(name)
+	}
+
+	if p.config.Partitions < 2 {
+		return newFileTopic(files, p.config, p.codec, compression)
+	}
+
+	t := &partitionedTopic{files: files, partitions: make([]*fileTopic, p.config.Partitions)}
+	for i := range t.partitions {
+		t.partitions[i] = newFileTopic(files.Partition(i), p.config, p.codec, compression)
+	}
+	return t
+}
+
 func (p *filePub) Close() (err error) {
+	if p.syncer != nil {
+		_ = p.syncer.Close()
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -72,32 +235,208 @@ func (p *filePub) Close() (err error) {
 	return
 }
 
+// syncLoop periodically syncs topics with FsyncInterval policy.
+func (p *filePub) syncLoop() {
+	ticker := time.NewTicker(p.config.FsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.syncer.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		for _, topic := range p.topics {
+			topic.syncDirty()
+		}
+		p.mu.RUnlock()
+	}
+}
+
 // --------------------------------------------------------------------
 
-type fileTopic struct {
-	name string
-	file *os.File
-	enc  *json.Encoder
-	mu   sync.Mutex
+// pubTopic is a publisher topic handle.
+type pubTopic interface {
+	bps.PubTopic
+	syncDirty()
+	Close() error
 }
 
-func (t *fileTopic) Publish(ctx context.Context, msg *bps.PubMessage) error {
+// partitionedTopic distributes messages across partition files.
+type partitionedTopic struct {
+	files      topicFiles
+	partitions []*fileTopic
+	next       uint32 // round-robin counter for messages without IDs
+
+	created uint32 // partition files are created
+	mu      sync.Mutex
+}
+
+func (t *partitionedTopic) Publish(ctx context.Context, msg *bps.PubMessage) error {
+	if err := t.create(); err != nil {
+		return err
+	}
+	return t.partitions[t.partition(msg.ID)].Publish(ctx, msg)
+}
+
+func (t *partitionedTopic) Close() (err error) {
+	for _, p := range t.partitions {
+		if e := p.Close(); e != nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (t *partitionedTopic) syncDirty() {
+	for _, p := range t.partitions {
+		p.syncDirty()
+	}
+}
+
+// create creates the topic directory and all partition files on the first publish,
+// so subscribers find empty partitions too.
+func (t *partitionedTopic) create() error {
+	if atomic.LoadUint32(&t.created) == 1 {
+		return nil
+	}
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if t.file == nil {
-		file, err := os.OpenFile(t.name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-		if err != nil {
+	if atomic.LoadUint32(&t.created) == 1 {
+		return nil
+	}
+	if err := t.files.CreatePartitions(len(t.partitions)); err != nil {
+		return err
+	}
+	for _, p := range t.partitions {
+		if err := p.create(); err != nil {
 			return err
 		}
-		t.file = file
-		t.enc = json.NewEncoder(file)
 	}
+	atomic.StoreUint32(&t.created, 1)
+	return nil
+}
+
+// partition returns the partition of a message ID, IDs are hashed like by the sarama (kafka) hash partitioner.
+func (t *partitionedTopic) partition(id string) int {
+	n := len(t.partitions)
+	if id == "" {
+		return int((atomic.AddUint32(&t.next, 1) - 1) % uint32(n))
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+	partition := int32(hash.Sum32()) % int32(n)
+	if partition < 0 {
+		partition = -partition
+	}
+	return int(partition)
+}
+
+// --------------------------------------------------------------------
+
+// fsync syncs a file to disk.
+var fsync = (*os.File).Sync
+
+// syncRound is a periodic sync of messages, written since the previous one, used by FsyncInterval policy.
+type syncRound struct {
+	done chan struct{}
+	err  error
+}
+
+// Wait waits till the round is completed, returns its error.
+func (r *syncRound) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Fail records an error, which is returned once the round is completed.
+func (r *syncRound) Fail(err error) {
+	if r != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// Complete completes the round, unless it is nil.
+// A previously recorded error takes precedence.
+func (r *syncRound) Complete(err error) {
+	if r != nil {
+		r.Fail(err)
+		close(r.done)
+	}
+}
+
+type fileTopic struct {
+	files  topicFiles
+	config *PublisherConfig
+
+	file   *os.File
+	size   int64     // size of the active segment
+	opened time.Time // time the active segment was opened
+	codec  codec
+	buf    []byte
+	mu     sync.Mutex
+
+	// compression state, guarded by mu:
+	compression Compression
+	compressor  compressor // nil, unless compressed
+	pending     []byte     // encoded records, not compressed yet
+	buffered    uint64     // number of messages in pending
+	frame       []byte     // compressed frame buffer
+
+	dirty bool       // unsynced writes, guarded by mu
+	round *syncRound // pending FsyncInterval sync, guarded by mu
+
+	// FsyncGroup state, written is guarded by mu, the rest by syncMu (locked after mu):
+	written  uint64 // number of messages written to the file
+	synced   uint64 // number of synced messages
+	syncing  bool   // sync in progress
+	syncMu   sync.Mutex
+	syncCond *sync.Cond
+}
 
-	if err := t.enc.Encode(msg); err != nil {
+func newFileTopic(files topicFiles, config *PublisherConfig, codec codec, compression Compression) *fileTopic {
+	t := &fileTopic{files: files, config: config, codec: codec, compression: compression}
+	t.syncCond = sync.NewCond(&t.syncMu)
+	return t
+}
+
+func (t *fileTopic) Publish(ctx context.Context, msg *bps.PubMessage) error {
+	t.mu.Lock()
+
+	seq, err := t.write(msg)
+	if err != nil {
+		t.mu.Unlock()
+		return err
+	}
+
+	switch t.config.Fsync {
+	case FsyncInterval:
+		round := t.pendingRound()
+		t.mu.Unlock()
+		return round.Wait(ctx)
+	case FsyncGroup:
+		t.mu.Unlock()
+		return t.groupSync(seq)
+	default:
+		err := t.flush()
+		if err == nil {
+			err = fsync(t.file)
+		}
+		if err == nil {
+			t.dirty = false
+		}
+		t.mu.Unlock()
 		return err
 	}
-	return t.file.Sync()
 }
 
 func (t *fileTopic) Close() error {
@@ -105,7 +444,424 @@ func (t *fileTopic) Close() error {
 	defer t.mu.Unlock()
 
 	if t.file != nil {
-		return t.file.Close()
+		err := t.syncActive()
+		t.completeRound(err)
+		if e := t.file.Close(); e != nil {
+			err = e
+		}
+		t.file = nil
+
+		if t.compressor != nil {
+			if e := t.compressor.Close(); e != nil {
+				err = e
+			}
+			t.compressor = nil
+		}
+		return err
+	}
+	return nil
+}
+
+// create creates the active segment, unless it exists.
+func (t *fileTopic) create() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return t.open()
+	}
+	return nil
+}
+
+// write writes a message, returns its sequence number.
+// It must be called with mu locked.
+func (t *fileTopic) write(msg *bps.PubMessage) (uint64, error) {
+	buf, err := t.codec.Append(t.buf[:0], msg)
+	if err != nil {
+		return 0, err
+	}
+	t.buf = buf
+
+	if t.file == nil {
+		if err := t.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if t.compressor != nil {
+		t.pending = append(t.pending, t.buf...)
+		t.buffered++
+	} else {
+		if err := t.append(t.buf); err != nil {
+			return 0, err
+		}
+		t.written++
+	}
+
+	t.dirty = true
+	return t.written + t.buffered, nil
+}
+
+// append appends data to the active segment and rolls it, if necessary.
+// The active segment is locked while it is appended to, so several processes can share a topic.
+// It must be called with mu locked.
+func (t *fileTopic) append(data []byte) error {
+	if err := t.lock(); err != nil {
+		return err
+	}
+	defer t.unlock()
+
+	if t.shouldRoll(len(data)) {
+		if err := t.roll(); err != nil {
+			return err
+		}
+		if err := t.lock(); err != nil {
+			return err
+		}
+	}
+
+	n, err := t.file.Write(data)
+	t.size += int64(n)
+	return err
+}
+
+// lock locks the active segment, it reopens the active segment, if it was rolled (or removed)
+// by another process meanwhile. The active segment size is updated, as other processes may append to it.
+// It must be called with mu locked.
+func (t *fileTopic) lock() error {
+	for {
+		if t.file == nil {
+			if err := t.open(); err != nil {
+				return err
+			}
+		}
+		if err := lockFile(t.file); err != nil {
+			return err
+		}
+
+		cur, err := t.file.Stat()
+		if err != nil {
+			t.unlock()
+			return err
+		}
+		fi, err := os.Stat(t.files.Active())
+		if err == nil && os.SameFile(cur, fi) {
+			t.size = cur.Size()
+			return nil
+		} else if err != nil && !os.IsNotExist(err) {
+			t.unlock()
+			return err
+		}
+
+		// reopen the active segment:
+		err = t.syncFile()
+		if e := t.file.Close(); e != nil && err == nil {
+			err = e
+		}
+		t.file = nil
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unlock unlocks the active segment.
+func (t *fileTopic) unlock() {
+	if t.file != nil {
+		_ = unlockFile(t.file)
+	}
+}
+
+// flush writes pending records of compressed topics as a single frame.
+// It must be called with mu locked.
+func (t *fileTopic) flush() error {
+	if len(t.pending) == 0 {
+		return nil
+	}
+
+	frame, err := t.compressor.Compress(t.frame[:0], t.pending)
+	if err == nil {
+		t.frame = frame
+		err = t.append(t.frame)
+	}
+
+	// failed messages are not retried:
+	t.written += t.buffered
+	t.buffered = 0
+	t.pending = t.pending[:0]
+	return err
+}
+
+// groupSync waits till the message with the sequence number is synced.
+// One of the waiting publishers syncs all the messages, written so far, others wait for it.
+func (t *fileTopic) groupSync(seq uint64) error {
+	for {
+		t.syncMu.Lock()
+		for t.syncing && t.synced < seq {
+			t.syncCond.Wait()
+		}
+		done := t.synced >= seq
+		t.syncMu.Unlock()
+
+		if done {
+			return nil
+		}
+
+		t.mu.Lock()
+		t.syncMu.Lock()
+		if t.syncing || t.synced >= seq {
+			t.syncMu.Unlock()
+			t.mu.Unlock()
+			continue
+		}
+		t.syncMu.Unlock()
+
+		// write pending messages first, the active segment may be rolled meanwhile:
+		if err := t.flush(); err != nil {
+			t.mu.Unlock()
+			return err
+		}
+
+		t.syncMu.Lock()
+		t.syncing = true
+		file, target := t.file, t.written
+		t.dirty = false
+		t.syncMu.Unlock()
+		t.mu.Unlock()
+
+		err := fsync(file)
+
+		t.syncMu.Lock()
+		t.syncing = false
+		if err == nil && target > t.synced {
+			t.synced = target
+		}
+		t.syncCond.Broadcast()
+		t.syncMu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// syncDirty syncs unsynced writes and completes the pending sync round, used by FsyncInterval policy.
+// Messages, written while the active segment is synced, are synced by the next round.
+func (t *fileTopic) syncDirty() {
+	t.mu.Lock()
+	round := t.round
+	t.round = nil
+
+	if !t.dirty || t.file == nil {
+		t.mu.Unlock()
+		round.Complete(nil)
+		return
+	}
+
+	// write pending messages first:
+	if err := t.flush(); err != nil {
+		t.mu.Unlock()
+		round.Complete(err)
+		return
+	}
+
+	t.syncMu.Lock()
+	t.syncing = true
+	file := t.file
+	t.dirty = false
+	t.syncMu.Unlock()
+	t.mu.Unlock()
+
+	err := fsync(file)
+
+	t.syncMu.Lock()
+	t.syncing = false
+	t.syncCond.Broadcast()
+	t.syncMu.Unlock()
+
+	round.Complete(err)
+}
+
+// pendingRound returns the pending sync round, which covers messages written so far.
+// It must be called with mu locked.
+func (t *fileTopic) pendingRound() *syncRound {
+	if t.round == nil {
+		t.round = &syncRound{done: make(chan struct{})}
+	}
+	return t.round
+}
+
+// completeRound completes the pending sync round.
+// It must be called with mu locked.
+func (t *fileTopic) completeRound(err error) {
+	t.round.Complete(err)
+	t.round = nil
+}
+
+// syncActive writes pending messages and syncs the active segment before it is closed.
+// It must be called with mu locked.
+func (t *fileTopic) syncActive() error {
+	if err := t.flush(); err != nil {
+		return err
+	}
+	return t.syncFile()
+}
+
+// syncFile syncs the active segment before it is closed.
+// It must be called with mu locked.
+func (t *fileTopic) syncFile() error {
+	// wait for an in-progress group sync:
+	t.syncMu.Lock()
+	for t.syncing {
+		t.syncCond.Wait()
+	}
+	t.syncMu.Unlock()
+
+	if t.dirty && t.file != nil {
+		if err := fsync(t.file); err != nil {
+			// report to messages, waiting for the pending sync round, as they may be lost:
+			t.round.Fail(err)
+			return err
+		}
+		t.dirty = false
+	}
+
+	t.syncMu.Lock()
+	t.synced = t.written
+	t.syncCond.Broadcast()
+	t.syncMu.Unlock()
+	return nil
+}
+
+// open opens the active segment for writing.
+func (t *fileTopic) open() error {
+	file, err := os.OpenFile(t.files.Active(), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		if ok, _ := dirExists(t.files.Active()); ok {
+			return fmt.Errorf("topic %s is partitioned, no partitions are configured", t.files.name)
+		}
+		return err
+	}
+
+	fi, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	// ensure existing data is compressed the same way:
+	if fi.Size() != 0 {
+		head := make([]byte, len(zstdMagic))
+		n, err := file.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			_ = file.Close()
+			return err
+		}
+		if c, ok := sniffCompression(head[:n]); ok && c != t.compression {
+			_ = file.Close()
+			return fmt.Errorf("topic %s has %s compression, %s is configured", t.files.name, c, t.compression)
+		}
+	}
+
+	if t.compressor == nil {
+		if t.compressor, err = newCompressor(t.compression); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+
+	t.file = file
+	t.size = fi.Size()
+	t.opened = time.Now()
+	return nil
+}
+
+// shouldRoll reports whether the active segment must be rolled before writing n bytes.
+func (t *fileTopic) shouldRoll(n int) bool {
+	if t.size == 0 {
+		return false
+	}
+	if t.config.SegmentBytes > 0 && t.size+int64(n) > int64(t.config.SegmentBytes) {
+		return true
+	}
+	if t.config.SegmentAge > 0 && time.Since(t.opened) >= t.config.SegmentAge {
+		return true
+	}
+	return false
+}
+
+// roll moves the active segment to rolled segments, opens a new one and applies retention.
+// The active segment must be locked, it is renamed before it is closed (and unlocked),
+// so other processes cannot append to a rolled segment.
+func (t *fileTopic) roll() error {
+	segs, err := t.files.Segments()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.files.SegmentDir(), 0777); err != nil {
+		return err
+	}
+
+	if err := t.syncFile(); err != nil {
+		return err
+	}
+
+	base := activeBase(segs)
+	if err := os.Rename(t.files.Active(), t.files.Segment(base)); err != nil {
+		return err
+	}
+	segs = append(segs, segment{Path: t.files.Segment(base), Base: base, Size: t.size, ModTime: time.Now()})
+
+	err = t.file.Close()
+	t.file = nil
+	if err != nil {
+		return err
+	}
+
+	if err := t.open(); err != nil {
+		return err
+	}
+	return t.retain(segs)
+}
+
+// retain removes (or archives) the oldest rolled segments, exceeding retention limits.
+// The newest rolled segment is always kept, as it determines the base offset of the active segment.
+func (t *fileTopic) retain(segs []segment) error {
+	var total int64
+	for _, seg := range segs {
+		total += seg.Size
+	}
+
+	for _, seg := range segs[:len(segs)-1] {
+		expired := t.config.RetentionAge > 0 && time.Since(seg.ModTime) > t.config.RetentionAge
+		if !expired && (t.config.RetentionBytes <= 0 || total <= int64(t.config.RetentionBytes)) {
+			break
+		}
+
+		if err := t.remove(seg); err != nil {
+			return fmt.Errorf("retention: %w", err)
+		}
+		total -= seg.Size
+	}
+	return nil
+}
+
+// remove removes or archives a rolled segment.
+// Segments, removed by another process meanwhile, are ignored.
+func (t *fileTopic) remove(seg segment) error {
+	if t.config.ArchiveDir == "" {
+		if err := os.Remove(seg.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	dir := filepath.Join(t.config.ArchiveDir, t.files.name)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	if err := os.Rename(seg.Path, filepath.Join(dir, filepath.Base(seg.Path))); err != nil && !os.IsNotExist(err) {
+		return err
 	}
 	return nil
 }
@@ -113,27 +869,208 @@ func (t *fileTopic) Close() error {
 // --------------------------------------------------------------------
 
 type fileSub struct {
-	root string
+	root   string
+	config *SubscriberConfig
 }
 
-// NewSubscriber inits a subscriber within a root directory.
+// NewSubscriber inits a subscriber within a root directory with default settings.
 // It assumes, that file is not being written to anymore.
-// It starts handling from the first (oldest) available message.
+// It starts handling from the first (oldest) available message by default.
 // It iterates entire file (all records) without tracking processed messages.
 func NewSubscriber(root string) bps.Subscriber {
+	return NewSubscriberWithConfig(root, nil)
+}
+
+// NewSubscriberWithConfig inits a subscriber within a root directory.
+// Unless config.Follow is set, it assumes, that file is not being written to anymore.
+// It starts handling from the first (oldest) available message by default.
+// Unless config.Consumer is set, it iterates entire file (all records) without tracking processed messages,
+// named consumers resume from the offset of the last handled message.
+// Subscriptions support bps.WithHandlerFactory to handle partitions in parallel.
+// Config is optional.
+func NewSubscriberWithConfig(root string, config *SubscriberConfig) bps.Subscriber {
 	return &fileSub{
-		root: root,
+		root:   root,
+		config: config.norm(),
 	}
 }
 
 func (s *fileSub) Topic(name string) bps.SubTopic {
-	return SubTopic(filepath.Join(s.root, name))
+	return &subTopic{files: topicFiles{root: s.root, name: name}, config: s.config}
 }
 
 func (s *fileSub) Close() error {
 	return nil
 }
 
+// --------------------------------------------------------------------
+
+type fileAdmin struct {
+	root string
+}
+
+// NewAdmin inits an admin within a root directory.
+// Topics are files, topics with more than one partition are directories of partition files,
+// replication and retention are not supported. Topics with rolled segments and partitioned topics
+// are managed as a whole, deleting a topic deletes its rolled segments and consumer checkpoints.
+func NewAdmin(root string) (bps.Admin, error) {
+	if err := os.MkdirAll(root, 0777); err != nil {
+		return nil, err
+	}
+	return &fileAdmin{root: root}, nil
+}
+
+func (a *fileAdmin) CreateTopic(_ context.Context, name string, config *bps.TopicConfig) error {
+	files, err := a.topicFiles(name)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := files.Exists(); err != nil {
+		return err
+	} else if ok {
+		return bps.ErrTopicExists
+	}
+
+	if config != nil && config.Partitions > 1 {
+		if err := os.Mkdir(files.Active(), 0777); os.IsExist(err) {
+			return bps.ErrTopicExists
+		} else if err != nil {
+			return err
+		}
+		if err := files.CreatePartitions(config.Partitions); err != nil {
+			return err
+		}
+		for i := 0; i < config.Partitions; i++ {
+			if err := createFile(files.Partition(i).Active()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := createFile(files.Active()); os.IsExist(err) {
+		return bps.ErrTopicExists
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *fileAdmin) DeleteTopic(_ context.Context, name string) error {
+	files, err := a.topicFiles(name)
+	if err != nil {
+		return err
+	}
+
+	n, err := files.Partitions()
+	if os.IsNotExist(err) {
+		return bps.ErrTopicNotFound
+	} else if err != nil {
+		return err
+	}
+
+	// partitioned topic directories contain all partition files:
+	if n != 0 {
+		return os.RemoveAll(files.Active())
+	}
+
+	if err := os.Remove(files.Active()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// remove rolled segments and consumer checkpoints too:
+	if err := os.RemoveAll(files.SegmentDir()); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(a.root, checkpointDir, name))
+}
+
+func (a *fileAdmin) ListTopics(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(a.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		} else if entry.IsDir() {
+			// include partitioned topics:
+			if _, err := (topicFiles{root: a.root, name: entry.Name()}).Partitions(); err == nil {
+				names = append(names, entry.Name())
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+
+	// include topics, that only have rolled segments:
+	entries, err = os.ReadDir(filepath.Join(a.root, segmentDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	sort.Strings(names)
+
+	uniq := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			uniq = append(uniq, name)
+		}
+	}
+	return uniq, nil
+}
+
+func (a *fileAdmin) DescribeTopic(_ context.Context, name string) (*bps.TopicInfo, error) {
+	files, err := a.topicFiles(name)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := files.Partitions()
+	if os.IsNotExist(err) {
+		return nil, bps.ErrTopicNotFound
+	} else if err != nil {
+		return nil, err
+	} else if n == 0 {
+		n = 1
+	}
+	return &bps.TopicInfo{Name: name, TopicConfig: bps.TopicConfig{Partitions: n}}, nil
+}
+
+// topicFiles validates the topic name and returns topic files.
+// Names of sub-directories, reserved for segments and checkpoints, are not valid topic names.
+func (a *fileAdmin) topicFiles(name string) (topicFiles, error) {
+	switch name {
+	case "", ".", "..", segmentDir, checkpointDir:
+		return topicFiles{}, fmt.Errorf("invalid topic name %q", name)
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return topicFiles{}, fmt.Errorf("invalid topic name %q", name)
+	}
+	return topicFiles{root: a.root, name: name}, nil
+}
+
+func (a *fileAdmin) Close() error {
+	return nil
+}
+
+// createFile creates an empty file, it fails if the file exists.
+func createFile(name string) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 // ----------------------------------------------------------------------------
 
 // SubTopic is an adapter for filename, that behaves like bps.SubTopic.
@@ -142,39 +1079,263 @@ type SubTopic string
 
 // Subscribe subscribes/consumes records from file.
 func (t SubTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps.Subscription, error) {
-	opts := (&bps.SubOptions{}).Apply(options)
+	return (&subTopic{files: topicFilesFor(string(t)), config: (*SubscriberConfig)(nil).norm()}).Subscribe(handler, options...)
+}
+
+type subTopic struct {
+	files  topicFiles
+	config *SubscriberConfig
+}
+
+func (t *subTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps.Subscription, error) {
+	opts := (&bps.SubOptions{
+		StartAt: bps.PositionOldest,
+	}).Apply(options)
+
+	switch opts.StartAt {
+	case bps.PositionOldest, bps.PositionNewest:
+	default:
+		return nil, fmt.Errorf("start position %s is not supported by this implementation", opts.StartAt)
+	}
 
-	f, err := os.Open(string(t))
+	if name := t.config.Consumer; name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid consumer name %q", name)
+	}
+
+	codec, err := newCodec(t.config.Format)
 	if err != nil {
 		return nil, err
 	}
 
 	sub := concurrent.NewGroup(context.Background())
+	n, err := t.files.Partitions()
+	if os.IsNotExist(err) && t.config.Follow {
+		sub.Go(func() {
+			ticker := time.NewTicker(t.config.PollInterval)
+			defer ticker.Stop()
+
+			// wait for the topic to be created, all its messages are new:
+			for {
+				select {
+				case <-sub.Done():
+					return
+				case <-ticker.C:
+				}
+
+				if n, err = t.files.Partitions(); err == nil {
+					break
+				} else if !os.IsNotExist(err) {
+					opts.ErrorHandler(err)
+					return
+				}
+			}
+
+			created := *opts
+			created.StartAt = bps.PositionOldest
+			if err := t.start(sub, n, codec, handler, &created); err != nil {
+				opts.ErrorHandler(err)
+			}
+		})
+		return sub, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := t.start(sub, n, codec, handler, opts); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// start starts consuming partitions of a topic with n partitions (0 if it is not partitioned).
+func (t *subTopic) start(sub *concurrent.Group, n int, codec codec, handler bps.Handler, opts *bps.SubOptions) error {
+	partitions, err := t.partitions(n)
+	if err != nil {
+		return err
+	}
+
+	// synchronize handler access, unless partitions are handled individually:
+	if len(partitions) > 1 {
+		handler = bps.SafeHandler(handler)
+	}
+	handlerFor := func(int) bps.Handler { return handler }
+	if opts.HandlerFactory != nil {
+		handlerFor = opts.HandlerFactory
+	}
+
+	if n == 0 {
+		return t.consume(sub, t.files, codec, handlerFor(0), opts)
+	}
+
+	for _, partition := range partitions {
+		if err := t.consume(sub, t.files.Partition(partition), codec, handlerFor(partition), opts); err != nil {
+			return fmt.Errorf("consume partition %d: %w", partition, err)
+		}
+	}
+	return nil
+}
+
+// partitions returns partitions to consume of a topic with n partitions.
+func (t *subTopic) partitions(n int) ([]int, error) {
+	if n < 1 {
+		n = 1
+	}
+
+	if len(t.config.ConsumePartitions) == 0 {
+		partitions := make([]int, n)
+		for i := range partitions {
+			partitions[i] = i
+		}
+		return partitions, nil
+	}
+
+	seen := make(map[int]bool, len(t.config.ConsumePartitions))
+	for _, partition := range t.config.ConsumePartitions {
+		if partition < 0 || partition >= n {
+			return nil, fmt.Errorf("invalid partition %d, topic has %d partition(s)", partition, n)
+		} else if seen[partition] {
+			return nil, fmt.Errorf("duplicate partition %d", partition)
+		}
+		seen[partition] = true
+	}
+	return t.config.ConsumePartitions, nil
+}
+
+// consume consumes a topic (partition) file within the subscription group.
+func (t *subTopic) consume(sub *concurrent.Group, files topicFiles, codec codec, handler bps.Handler, opts *bps.SubOptions) error {
+	var cp *checkpoint
+	offset := int64(-1)
+	if name := t.config.Consumer; name != "" {
+		var err error
+		if cp, offset, err = openCheckpoint(files.Checkpoint(name)); err != nil {
+			return err
+		}
+	}
+
+	r := newTopicReader(files, codec)
+	opened := true
+	if err := t.seek(r, offset, opts.StartAt); os.IsNotExist(err) && t.config.Follow {
+		opened = false
+	} else if err != nil {
+		_ = r.Close()
+		closeCheckpoint(cp)
+		return err
+	}
 
 	sub.Go(func() {
-		defer f.Close()
+		defer closeCheckpoint(cp)
+		defer r.Close()
 
-		dec := json.NewDecoder(f)
-		for dec.More() {
+		ticker := time.NewTicker(t.config.PollInterval)
+		defer ticker.Stop()
+
+		// wait for the topic to be created, all its messages are new:
+		for !opened {
 			select {
 			case <-sub.Done():
 				return
-			default:
+			case <-ticker.C:
 			}
 
-			var msg subMessage
-			if err := dec.Decode(&msg); err != nil {
+			if err := t.seek(r, offset, bps.PositionOldest); err == nil {
+				opened = true
+			} else if !os.IsNotExist(err) {
 				opts.ErrorHandler(err)
+				return
+			}
+		}
+
+		// commit stores the offset of handled messages:
+		commit := func() {
+			if cp != nil {
+				if err := cp.Commit(r.Offset()); err != nil {
+					opts.ErrorHandler(err)
+				}
+			}
+		}
+
+		for {
+			select {
+			case <-sub.Done():
+				return
+			default:
+			}
+
+			record, err := r.ReadRecord()
+			if err == io.EOF {
+				if !t.config.Follow {
+					// handle the last line, even if it is not terminated:
+					if record, err := r.ReadPending(); err != nil {
+						opts.ErrorHandler(err)
+					} else if record != nil {
+						handleRecord(codec, record, handler, opts.ErrorHandler)
+						commit()
+					}
+					return
+				}
+
+				select {
+				case <-sub.Done():
+					return
+				case <-ticker.C:
+				}
 				continue
+			} else if err != nil {
+				opts.ErrorHandler(err)
+				return
 			}
 
-			handler.Handle(msg)
+			handleRecord(codec, record, handler, opts.ErrorHandler)
+			commit()
 		}
 	})
+	return nil
+}
 
-	return sub, nil
+// seek positions reader at the checkpoint offset (if there is one) or the start position.
+func (t *subTopic) seek(r *topicReader, offset int64, pos bps.StartPosition) error {
+	if offset > -1 {
+		return r.SeekTo(offset)
+	} else if pos == bps.PositionNewest {
+		return r.SeekNewest()
+	}
+	return r.SeekOldest()
+}
+
+func closeCheckpoint(cp *checkpoint) {
+	if cp != nil {
+		_ = cp.Close()
+	}
+}
+
+func handleRecord(codec codec, record []byte, handler bps.Handler, errorHandler func(error)) {
+	msg, err := codec.Decode(record)
+	if err != nil {
+		errorHandler(err)
+		return
+	} else if msg == nil {
+		return
+	}
+	handler.Handle(&SubMessage{msg: msg})
+}
+
+// SubMessage is a message, handled by subscriptions. It implements the bps.SubMessage interface.
+type SubMessage struct {
+	msg *bps.PubMessage
 }
 
-type subMessage struct{ bps.PubMessage }
+// Data implements the bps.SubMessage interface.
+func (m *SubMessage) Data() []byte {
+	return m.msg.Data
+}
 
-func (m subMessage) Data() []byte { return m.PubMessage.Data }
+// ID returns the message ID, if it is supported by the format.
+func (m *SubMessage) ID() string {
+	return m.msg.ID
+}
+
+// Attributes returns the message attributes, if they are supported by the format.
+func (m *SubMessage) Attributes() map[string]string {
+	return m.msg.Attributes
+}
@@ -1,12 +1,20 @@
 package file_test
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/bsm/bps"
 	"github.com/bsm/bps/file"
@@ -38,6 +46,11 @@ var _ = Describe("Publisher", func() {
 		Expect(subject).NotTo(BeNil())
 	})
 
+	It("should fail on unknown URL parameters", func() {
+		_, err := bps.NewPublisher(ctx, "file://"+dir+"?foo=bar")
+		Expect(err).To(MatchError(`invalid URL query: unknown parameter "foo"`))
+	})
+
 	Context("lint", func() {
 		var shared lint.PublisherInput
 
@@ -94,6 +107,39 @@ var _ = Describe("Subscriber", func() {
 		Expect(subject).NotTo(BeNil())
 	})
 
+	It("should fail on invalid URL parameters", func() {
+		_, err := bps.NewSubscriber(ctx, "file://"+dir+"?follow=maybe")
+		Expect(err).To(MatchError(ContainSubstring(`follow`)))
+	})
+
+	It("should stop at the end of file", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "topic"), []byte(`{"data":"djE="}`+"\n"+`{"data":"djI="}`), 0666)).To(Succeed())
+
+		received := make(chan string, 10)
+		sub, err := subject.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		Eventually(received).Should(Receive(Equal("v1")))
+		Eventually(received).Should(Receive(Equal("v2")))
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should init with default settings", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "topic"), []byte(`{"data":"djE="}`+"\n"), 0666)).To(Succeed())
+
+		received := make(chan string, 10)
+		sub, err := file.NewSubscriber(dir).Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		Eventually(received).Should(Receive(Equal("v1")))
+	})
+
 	Context("lint", func() {
 		var shared lint.SubscriberInput
 
@@ -110,6 +156,1112 @@ var _ = Describe("Subscriber", func() {
 	})
 })
 
+var _ = Describe("Subscriber (follow)", func() {
+	var subject bps.Subscriber
+	var ctx = context.Background()
+	var dir string
+	var received chan string
+
+	handler := bps.HandlerFunc(func(msg bps.SubMessage) {
+		received <- string(msg.Data())
+	})
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+
+		subject, err = bps.NewSubscriber(ctx, "file://"+dir+"?follow=true&poll_interval=10ms")
+		Expect(err).NotTo(HaveOccurred())
+
+		received = make(chan string, 10)
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should consume appended messages", func() {
+		seedTopic(dir, "topic", []bps.SubMessage{bps.RawSubMessage("v1")})
+
+		sub, err := subject.Topic("topic").Subscribe(handler)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		Eventually(received).Should(Receive(Equal("v1")))
+
+		seedTopic(dir, "topic", []bps.SubMessage{bps.RawSubMessage("v2"), bps.RawSubMessage("v3")})
+		Eventually(received).Should(Receive(Equal("v2")))
+		Eventually(received).Should(Receive(Equal("v3")))
+	})
+
+	It("should wait for topics to be created", func() {
+		sub, err := subject.Topic("topic").Subscribe(handler)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+
+		seedTopic(dir, "topic", []bps.SubMessage{bps.RawSubMessage("v1")})
+		Eventually(received).Should(Receive(Equal("v1")))
+	})
+
+	It("should handle partially written lines", func() {
+		f, err := os.Create(filepath.Join(dir, "topic"))
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+
+		sub, err := subject.Topic("topic").Subscribe(handler)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		_, err = f.WriteString(`{"data":"djE="}` + "\n" + `{"data":`)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(received).Should(Receive(Equal("v1")))
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+
+		_, err = f.WriteString(`"djI="}` + "\n")
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(received).Should(Receive(Equal("v2")))
+	})
+
+	It("should start at the end of file with PositionNewest", func() {
+		f, err := os.Create(filepath.Join(dir, "topic"))
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+
+		_, err = f.WriteString(`{"data":"djE="}` + "\n" + `{"data":`)
+		Expect(err).NotTo(HaveOccurred())
+
+		sub, err := subject.Topic("topic").Subscribe(handler, bps.StartAt(bps.PositionNewest))
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		_, err = f.WriteString(`"djI="}` + "\n" + `{"data":"djM="}` + "\n")
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(received).Should(Receive(Equal("v3")))
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+	})
+})
+
+var _ = Describe("Publisher (segments)", func() {
+	var subject bps.Publisher
+	var ctx = context.Background()
+	var dir string
+
+	publish := func(data ...string) {
+		for _, d := range data {
+			Expect(subject.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte(d)})).To(Succeed())
+		}
+	}
+
+	segments := func(dir string) []string {
+		entries, err := filepath.Glob(filepath.Join(dir, "*"))
+		Expect(err).NotTo(HaveOccurred())
+		for i, entry := range entries {
+			entries[i] = filepath.Base(entry)
+		}
+		return entries
+	}
+
+	consume := func(url string, n int) []string {
+		sub, err := bps.NewSubscriber(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		var res []string
+		for i := 0; i < n; i++ {
+			var data string
+			Eventually(received).Should(Receive(&data))
+			res = append(res, data)
+		}
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+		return res
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should roll segments by size", func() {
+		var err error
+		subject, err = bps.NewPublisher(ctx, "file://"+dir+"?segment_bytes=40")
+		Expect(err).NotTo(HaveOccurred())
+
+		publish("v1", "v2", "v3", "v4", "v5") // 16 bytes each
+		Expect(segments(filepath.Join(dir, ".segments", "topic"))).To(Equal([]string{
+			"00000000000000000000",
+			"00000000000000000032",
+		}))
+		Expect(consume("file://"+dir, 5)).To(Equal([]string{"v1", "v2", "v3", "v4", "v5"}))
+	})
+
+	It("should roll segments by age", func() {
+		var err error
+		subject, err = bps.NewPublisher(ctx, "file://"+dir+"?segment_age=20ms")
+		Expect(err).NotTo(HaveOccurred())
+
+		publish("v1", "v2")
+		time.Sleep(30 * time.Millisecond)
+		publish("v3")
+		Expect(segments(filepath.Join(dir, ".segments", "topic"))).To(Equal([]string{"00000000000000000000"}))
+		Expect(consume("file://"+dir, 3)).To(Equal([]string{"v1", "v2", "v3"}))
+	})
+
+	It("should apply retention", func() {
+		var err error
+		subject, err = bps.NewPublisher(ctx, "file://"+dir+"?segment_bytes=16&retention_bytes=32")
+		Expect(err).NotTo(HaveOccurred())
+
+		publish("v1", "v2", "v3", "v4", "v5")
+		Expect(segments(filepath.Join(dir, ".segments", "topic"))).To(Equal([]string{
+			"00000000000000000032",
+			"00000000000000000048",
+		}))
+		Expect(consume("file://"+dir, 3)).To(Equal([]string{"v3", "v4", "v5"}))
+	})
+
+	It("should archive segments", func() {
+		archive := filepath.Join(dir, "archive")
+
+		var err error
+		subject, err = bps.NewPublisher(ctx, "file://"+dir+"?segment_bytes=16&retention_bytes=16&archive_dir="+archive)
+		Expect(err).NotTo(HaveOccurred())
+
+		publish("v1", "v2", "v3")
+		Expect(segments(filepath.Join(dir, ".segments", "topic"))).To(Equal([]string{"00000000000000000016"}))
+		Expect(segments(filepath.Join(archive, "topic"))).To(Equal([]string{"00000000000000000000"}))
+	})
+
+	It("should share topics between publishers", func() {
+		var err error
+		subject, err = bps.NewPublisher(ctx, "file://"+dir+"?segment_bytes=100&fsync=group")
+		Expect(err).NotTo(HaveOccurred())
+
+		// publishers in other processes open topic files separately:
+		other, err := file.NewPublisherWithConfig(dir, &file.PublisherConfig{SegmentBytes: 100})
+		Expect(err).NotTo(HaveOccurred())
+		defer other.Close()
+
+		var wg sync.WaitGroup
+		var expected []string
+		for i, pub := range []bps.Publisher{subject, other} {
+			for j := 0; j < 4; j++ {
+				wg.Add(1)
+				prefix := string(rune('a'+i)) + strconv.Itoa(j)
+				go func(pub bps.Publisher) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					for k := 0; k < 10; k++ {
+						Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte(prefix + strconv.Itoa(k))})).To(Succeed())
+					}
+				}(pub)
+
+				for k := 0; k < 10; k++ {
+					expected = append(expected, prefix+strconv.Itoa(k))
+				}
+			}
+		}
+		wg.Wait()
+
+		// nothing is appended to rolled segments:
+		entries, err := os.ReadDir(filepath.Join(dir, ".segments", "topic"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(entries)).To(BeNumerically(">", 1))
+
+		var offset int64
+		for _, entry := range entries {
+			Expect(entry.Name()).To(Equal(fmt.Sprintf("%020d", offset)))
+
+			fi, err := entry.Info()
+			Expect(err).NotTo(HaveOccurred())
+			offset += fi.Size()
+		}
+
+		Expect(consume("file://"+dir, len(expected))).To(ConsistOf(expected))
+	})
+
+	It("should follow topics across segments", func() {
+		var err error
+		subject, err = bps.NewPublisher(ctx, "file://"+dir+"?segment_bytes=40")
+		Expect(err).NotTo(HaveOccurred())
+		publish("v1")
+
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+"?follow=true&poll_interval=5ms&consumer=a")
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		Eventually(received).Should(Receive(Equal("v1")))
+		for _, data := range []string{"v2", "v3", "v4", "v5"} {
+			publish(data)
+			Eventually(received).Should(Receive(Equal(data)))
+		}
+		Expect(subscription.Close()).To(Succeed())
+
+		publish("v6", "v7")
+		Expect(consume("file://"+dir+"?consumer=a", 2)).To(Equal([]string{"v6", "v7"}))
+	})
+
+	It("should fail on invalid URL parameters", func() {
+		var err error
+		subject, err = bps.NewPublisher(ctx, "file://"+dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = bps.NewPublisher(ctx, "file://"+dir+"?segment_bytes=big")
+		Expect(err).To(MatchError(ContainSubstring(`segment_bytes`)))
+	})
+})
+
+var _ = Describe("Publisher (fsync)", func() {
+	var ctx = context.Background()
+	var dir string
+
+	readTopic := func() []string {
+		segments, err := filepath.Glob(filepath.Join(dir, ".segments", "topic", "*"))
+		Expect(err).NotTo(HaveOccurred())
+
+		var lines []string
+		for _, name := range append(segments, filepath.Join(dir, "topic")) {
+			data, err := os.ReadFile(name)
+			Expect(err).NotTo(HaveOccurred())
+			lines = append(lines, strings.Split(strings.TrimSpace(string(data)), "\n")...)
+		}
+		return lines
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	for _, query := range []string{
+		"?fsync=always",
+		"?fsync=interval&fsync_interval=5ms",
+		"?fsync=group",
+		"?fsync=group&segment_bytes=1000",
+	} {
+		query := query
+
+		It("should publish concurrently with "+query, func() {
+			pub, err := bps.NewPublisher(ctx, "file://"+dir+query)
+			Expect(err).NotTo(HaveOccurred())
+			defer pub.Close()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					for j := 0; j < 25; j++ {
+						Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v")})).To(Succeed())
+					}
+				}()
+			}
+			wg.Wait()
+			Expect(pub.Close()).To(Succeed())
+			Expect(readTopic()).To(HaveLen(200))
+		})
+	}
+
+	It("should share a single sync between concurrent publishers with fsync=group", func() {
+		var syncs int32
+		restore := file.SetFsync(func(f *os.File) error {
+			// hold the first sync until all messages are written:
+			if atomic.AddInt32(&syncs, 1) == 1 {
+				for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+					if fi, err := f.Stat(); err != nil || fi.Size() >= 10*int64(len(`{"data":"dg=="}`+"\n")) {
+						break
+					}
+				}
+			}
+			return f.Sync()
+		})
+		defer restore()
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?fsync=group")
+		Expect(err).NotTo(HaveOccurred())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v")})).To(Succeed())
+			}()
+		}
+		wg.Wait()
+		Expect(pub.Close()).To(Succeed())
+
+		// the first sync covers the first message(s), the second one covers the rest:
+		Expect(atomic.LoadInt32(&syncs)).To(BeNumerically("<=", 2))
+		Expect(readTopic()).To(HaveLen(10))
+	})
+
+	It("should wait for the next sync with fsync=interval", func() {
+		var syncs int32
+		restore := file.SetFsync(func(f *os.File) error {
+			atomic.AddInt32(&syncs, 1)
+			return f.Sync()
+		})
+		defer restore()
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?fsync=interval&fsync_interval=10ms")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+		Expect(atomic.LoadInt32(&syncs)).To(Equal(int32(1)))
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).To(Succeed())
+		Expect(atomic.LoadInt32(&syncs)).To(Equal(int32(2)))
+
+		Expect(pub.Close()).To(Succeed())
+		Expect(atomic.LoadInt32(&syncs)).To(Equal(int32(2)))
+		Expect(readTopic()).To(HaveLen(2))
+	})
+
+	It("should report sync errors to waiting publishers with fsync=interval", func() {
+		var failing int32 = 1
+		restore := file.SetFsync(func(f *os.File) error {
+			if atomic.LoadInt32(&failing) == 1 {
+				return errors.New("sync failed")
+			}
+			return f.Sync()
+		})
+		defer restore()
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?fsync=interval&fsync_interval=10ms")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(MatchError("sync failed"))
+
+		atomic.StoreInt32(&failing, 0)
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).To(Succeed())
+		Expect(pub.Close()).To(Succeed())
+	})
+
+	It("should fail on invalid policies", func() {
+		_, err := bps.NewPublisher(ctx, "file://"+dir+"?fsync=never")
+		Expect(err).To(MatchError(ContainSubstring(`must be one of always, interval, group`)))
+	})
+})
+
+var _ = Describe("Formats", func() {
+	var ctx = context.Background()
+	var dir string
+
+	roundTrip := func(format string, messages ...*bps.PubMessage) []*bps.PubMessage {
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?format="+format)
+		Expect(err).NotTo(HaveOccurred())
+		for _, msg := range messages {
+			Expect(pub.Topic("topic").Publish(ctx, msg)).To(Succeed())
+		}
+		Expect(pub.Close()).To(Succeed())
+
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+"?format="+format)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan *bps.PubMessage, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			m := msg.(*file.SubMessage)
+			received <- &bps.PubMessage{ID: m.ID(), Data: m.Data(), Attributes: m.Attributes()}
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		var res []*bps.PubMessage
+		for range messages {
+			var msg *bps.PubMessage
+			Eventually(received).Should(Receive(&msg))
+			res = append(res, msg)
+		}
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+		return res
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should support jsonl", func() {
+		msgs := []*bps.PubMessage{
+			{ID: "1", Data: []byte("v1"), Attributes: map[string]string{"k": "v"}},
+			{Data: []byte{0, 1, '\n'}},
+		}
+		Expect(roundTrip("jsonl", msgs...)).To(Equal(msgs))
+	})
+
+	It("should support raw", func() {
+		Expect(roundTrip("raw",
+			&bps.PubMessage{ID: "1", Data: []byte("v1"), Attributes: map[string]string{"k": "v"}},
+			&bps.PubMessage{Data: []byte{}},
+			&bps.PubMessage{Data: []byte("v3")},
+		)).To(Equal([]*bps.PubMessage{
+			{Data: []byte("v1")},
+			{Data: []byte{}},
+			{Data: []byte("v3")},
+		}))
+		Expect(os.ReadFile(filepath.Join(dir, "topic"))).To(Equal([]byte("v1\n\nv3\n")))
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?format=raw")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("a\nb")})).
+			To(MatchError("raw format does not support line breaks in message data"))
+	})
+
+	It("should support binary", func() {
+		msgs := []*bps.PubMessage{
+			{ID: "1", Data: []byte("v1"), Attributes: map[string]string{"k1": "v1", "k2": "v2"}},
+			{Data: []byte{0, 1, '\n', 255}},
+			{ID: "3", Data: []byte{}},
+		}
+		Expect(roundTrip("binary", msgs...)).To(Equal(msgs))
+		Expect(os.ReadFile(filepath.Join(dir, "topic"))).To(HaveLen(3*4 + 18 + 7 + 4))
+	})
+
+	It("should support csv", func() {
+		msgs := []*bps.PubMessage{
+			{ID: "1", Data: []byte("v1"), Attributes: map[string]string{"k1": "v1", "k2": "v2"}},
+			{ID: "2", Data: []byte("multi\n\"line\", data")},
+			{ID: "3", Data: []byte{}},
+		}
+		Expect(roundTrip("csv", msgs...)).To(Equal(msgs))
+		Expect(os.ReadFile(filepath.Join(dir, "topic"))).To(Equal([]byte("1,v1,k1,v1,k2,v2\n" +
+			"2,\"multi\n\"\"line\"\", data\"\n" +
+			"3,\n")))
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?format=csv")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("a\r\nb")})).
+			To(MatchError("csv format does not support CRLF line breaks"))
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v4"), Attributes: map[string]string{"k": "a\r\nb"}})).
+			To(MatchError("csv format does not support CRLF line breaks"))
+	})
+
+	It("should handle partially written binary records", func() {
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?format=binary")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).To(Succeed())
+		Expect(pub.Close()).To(Succeed())
+
+		name := filepath.Join(dir, "topic")
+		data, err := os.ReadFile(name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(name, data[:len(data)-3], 0666)).To(Succeed())
+
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+"?format=binary&follow=true&poll_interval=5ms")
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		Eventually(received).Should(Receive(Equal("v1")))
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+
+		f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0666)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		_, err = f.Write(data[len(data)-3:])
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(received).Should(Receive(Equal("v2")))
+	})
+
+	It("should fail on invalid formats", func() {
+		_, err := bps.NewPublisher(ctx, "file://"+dir+"?format=xml")
+		Expect(err).To(MatchError(ContainSubstring(`must be one of jsonl, raw, binary, csv`)))
+
+		_, err = file.NewPublisherWithConfig(dir, &file.PublisherConfig{Format: "xml"})
+		Expect(err).To(MatchError(`unknown format "xml"`))
+	})
+})
+
+var _ = Describe("Compression", func() {
+	var ctx = context.Background()
+	var dir string
+
+	publish := func(query, topic string, data ...string) {
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+query)
+		Expect(err).NotTo(HaveOccurred())
+		for _, v := range data {
+			Expect(pub.Topic(topic).Publish(ctx, &bps.PubMessage{Data: []byte(v)})).To(Succeed())
+		}
+		Expect(pub.Close()).To(Succeed())
+	}
+
+	consume := func(query, topic string, n int) []string {
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+query)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic(topic).Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		var res []string
+		for i := 0; i < n; i++ {
+			var v string
+			Eventually(received).Should(Receive(&v))
+			res = append(res, v)
+		}
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+		return res
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should support gzip", func() {
+		publish("?compression=gzip", "topic", "v1", "v2")
+		publish("?compression=gzip&fsync=interval&fsync_interval=5ms", "topic", "v3", "v4")
+		Expect(consume("", "topic", 4)).To(Equal([]string{"v1", "v2", "v3", "v4"}))
+
+		f, err := os.Open(filepath.Join(dir, "topic"))
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+
+		zr, err := gzip.NewReader(f)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.ReadAll(zr)).To(Equal([]byte(`{"data":"djE="}` + "\n" +
+			`{"data":"djI="}` + "\n" +
+			`{"data":"djM="}` + "\n" +
+			`{"data":"djQ="}` + "\n")))
+	})
+
+	It("should support zstd", func() {
+		publish("?compression=zstd&format=binary", "topic", "v1", "v2")
+		publish("?compression=zstd&format=binary&fsync=group", "topic", "v3")
+		Expect(consume("?format=binary", "topic", 3)).To(Equal([]string{"v1", "v2", "v3"}))
+		Expect(os.ReadFile(filepath.Join(dir, "topic"))).To(HavePrefix("\x28\xb5\x2f\xfd"))
+	})
+
+	It("should infer compression from topic extensions", func() {
+		publish("", "topic.gz", "v1")
+		publish("", "topic.zst", "v2")
+		publish("?compression=none", "plain.gz", "v3")
+
+		Expect(os.ReadFile(filepath.Join(dir, "topic.gz"))).To(HavePrefix("\x1f\x8b"))
+		Expect(os.ReadFile(filepath.Join(dir, "topic.zst"))).To(HavePrefix("\x28\xb5\x2f\xfd"))
+		Expect(os.ReadFile(filepath.Join(dir, "plain.gz"))).To(Equal([]byte(`{"data":"djM="}` + "\n")))
+
+		Expect(consume("", "topic.gz", 1)).To(Equal([]string{"v1"}))
+		Expect(consume("", "topic.zst", 1)).To(Equal([]string{"v2"}))
+		Expect(consume("", "plain.gz", 1)).To(Equal([]string{"v3"}))
+	})
+
+	It("should follow compressed topics across segments", func() {
+		publish("?compression=zstd", "topic", "v1")
+
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+"?follow=true&poll_interval=5ms&consumer=c1")
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(received).Should(Receive(Equal("v1")))
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?compression=zstd&segment_bytes=64")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+
+		for _, v := range []string{"v2", "v3", "v4"} {
+			Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte(v)})).To(Succeed())
+			Eventually(received).Should(Receive(Equal(v)))
+		}
+		Expect(subscription.Close()).To(Succeed())
+
+		// resume from the checkpoint:
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v5")})).To(Succeed())
+		Expect(consume("?consumer=c1", "topic", 1)).To(Equal([]string{"v5"}))
+	})
+
+	It("should skip incomplete frames", func() {
+		publish("?compression=gzip", "topic", "v1", "v2")
+
+		name := filepath.Join(dir, "topic")
+		data, err := os.ReadFile(name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(name, data[:len(data)-3], 0666)).To(Succeed())
+		Expect(consume("", "topic", 1)).To(Equal([]string{"v1"}))
+	})
+
+	It("should reject compression mismatches", func() {
+		publish("", "topic", "v1")
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?compression=gzip")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).
+			To(MatchError("topic topic has none compression, gzip is configured"))
+	})
+
+	It("should fail on invalid compressions", func() {
+		_, err := bps.NewPublisher(ctx, "file://"+dir+"?compression=lz4")
+		Expect(err).To(MatchError(ContainSubstring(`must be one of none, gzip, zstd`)))
+
+		_, err = file.NewPublisherWithConfig(dir, &file.PublisherConfig{Compression: "lz4"})
+		Expect(err).To(MatchError(`unknown compression "lz4"`))
+	})
+})
+
+var _ = Describe("Partitions", func() {
+	var ctx = context.Background()
+	var dir string
+
+	publish := func(query string, messages ...*bps.PubMessage) {
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+query)
+		Expect(err).NotTo(HaveOccurred())
+		for _, msg := range messages {
+			Expect(pub.Topic("topic").Publish(ctx, msg)).To(Succeed())
+		}
+		Expect(pub.Close()).To(Succeed())
+	}
+
+	consume := func(query string, n int) []string {
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+query)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		var res []string
+		for i := 0; i < n; i++ {
+			var v string
+			Eventually(received).Should(Receive(&v))
+			res = append(res, v)
+		}
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+		return res
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should partition messages by ID", func() {
+		publish("?partitions=3",
+			&bps.PubMessage{ID: "a", Data: []byte("v1")},
+			&bps.PubMessage{ID: "k1", Data: []byte("v2")},
+			&bps.PubMessage{ID: "k2", Data: []byte("v3")},
+			&bps.PubMessage{ID: "a", Data: []byte("v4")},
+			&bps.PubMessage{ID: "k1", Data: []byte("v5")},
+		)
+
+		names, err := filepath.Glob(filepath.Join(dir, "topic", "[0-9]*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal([]string{
+			filepath.Join(dir, "topic", "0"),
+			filepath.Join(dir, "topic", "1"),
+			filepath.Join(dir, "topic", "2"),
+		}))
+		Expect(os.ReadFile(filepath.Join(dir, "topic", "2"))).To(BeEmpty())
+		Expect(os.ReadFile(filepath.Join(dir, "topic", ".partitions"))).To(Equal([]byte("3\n")))
+
+		Expect(consume("?consume_partitions=0", 2)).To(Equal([]string{"v1", "v4"}))
+		Expect(consume("?consume_partitions=1", 3)).To(Equal([]string{"v2", "v3", "v5"}))
+		Expect(consume("?consume_partitions=1,2", 3)).To(Equal([]string{"v2", "v3", "v5"}))
+		Expect(consume("", 5)).To(ConsistOf("v1", "v2", "v3", "v4", "v5"))
+	})
+
+	It("should distribute messages without IDs round-robin", func() {
+		publish("?partitions=2",
+			&bps.PubMessage{Data: []byte("v1")},
+			&bps.PubMessage{Data: []byte("v2")},
+			&bps.PubMessage{Data: []byte("v3")},
+		)
+		Expect(consume("?consume_partitions=0", 2)).To(Equal([]string{"v1", "v3"}))
+		Expect(consume("?consume_partitions=1", 1)).To(Equal([]string{"v2"}))
+	})
+
+	It("should handle partitions in parallel", func() {
+		publish("?partitions=2",
+			&bps.PubMessage{ID: "1", Data: []byte("v1")},
+			&bps.PubMessage{ID: "2", Data: []byte("v2")},
+			&bps.PubMessage{ID: "3", Data: []byte("v3")},
+			&bps.PubMessage{ID: "1", Data: []byte("v4")},
+		)
+
+		sub := file.NewSubscriberWithConfig(dir, &file.SubscriberConfig{Follow: true, PollInterval: 5 * time.Millisecond, Consumer: "c1"})
+		defer sub.Close()
+
+		var mu sync.Mutex
+		received := make(map[int][]string)
+		subscription, err := sub.Topic("topic").Subscribe(nil, bps.WithHandlerFactory(func(partition int) bps.Handler {
+			return bps.HandlerFunc(func(msg bps.SubMessage) {
+				mu.Lock()
+				received[partition] = append(received[partition], string(msg.Data()))
+				mu.Unlock()
+			})
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		Eventually(func() map[int][]string {
+			mu.Lock()
+			defer mu.Unlock()
+
+			res := make(map[int][]string, len(received))
+			for partition, data := range received {
+				res[partition] = append([]string{}, data...)
+			}
+			return res
+		}).Should(Equal(map[int][]string{
+			0: {"v1", "v3", "v4"},
+			1: {"v2"},
+		}))
+
+		// checkpoints are stored per partition:
+		Expect(filepath.Join(dir, "topic", ".checkpoints", "0", "c1")).To(BeAnExistingFile())
+		Expect(filepath.Join(dir, "topic", ".checkpoints", "1", "c1")).To(BeAnExistingFile())
+	})
+
+	It("should not collide with other topics", func() {
+		publish("?partitions=2", &bps.PubMessage{ID: "1", Data: []byte("v1")})
+
+		pub, err := file.NewPublisher(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Topic("topic-0").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).To(Succeed())
+		Expect(pub.Close()).To(Succeed())
+
+		Expect(filepath.Join(dir, "topic-0")).To(BeARegularFile())
+		Expect(consume("", 1)).To(Equal([]string{"v1"}))
+	})
+
+	It("should wait for partitioned topics in follow mode", func() {
+		sub := file.NewSubscriberWithConfig(dir, &file.SubscriberConfig{Follow: true, PollInterval: 5 * time.Millisecond})
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		publish("?partitions=2",
+			&bps.PubMessage{ID: "1", Data: []byte("v1")},
+			&bps.PubMessage{ID: "2", Data: []byte("v2")},
+		)
+		var v1, v2 string
+		Eventually(received).Should(Receive(&v1))
+		Eventually(received).Should(Receive(&v2))
+		Expect([]string{v1, v2}).To(ConsistOf("v1", "v2"))
+	})
+
+	It("should fail on partition mismatches", func() {
+		publish("?partitions=2", &bps.PubMessage{Data: []byte("v1")})
+
+		pub, err := bps.NewPublisher(ctx, "file://"+dir+"?partitions=3")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).
+			To(MatchError("topic topic has 2 partition(s), 3 are configured"))
+
+		plain, err := bps.NewPublisher(ctx, "file://"+dir)
+		Expect(err).NotTo(HaveOccurred())
+		defer plain.Close()
+		Expect(plain.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).
+			To(MatchError("topic topic is partitioned, no partitions are configured"))
+		Expect(plain.Topic("other").Publish(ctx, &bps.PubMessage{Data: []byte("v3")})).To(Succeed())
+
+		Expect(pub.Topic("other").Publish(ctx, &bps.PubMessage{Data: []byte("v4")})).
+			To(MatchError("topic other is not partitioned, 3 partitions are configured"))
+	})
+
+	It("should fail on invalid partitions", func() {
+		_, err := bps.NewPublisher(ctx, "file://"+dir+"?partitions=-1")
+		Expect(err).To(MatchError("invalid number of partitions: -1"))
+
+		_, err = bps.NewSubscriber(ctx, "file://"+dir+"?consume_partitions=1,x")
+		Expect(err).To(MatchError(ContainSubstring(`invalid consume_partitions value "1,x": not a list of integers`)))
+
+		publish("?partitions=3", &bps.PubMessage{Data: []byte("v1")})
+
+		sub := file.NewSubscriberWithConfig(dir, &file.SubscriberConfig{ConsumePartitions: []int{3}})
+		defer sub.Close()
+		_, err = sub.Topic("topic").Subscribe(bps.HandlerFunc(func(bps.SubMessage) {}))
+		Expect(err).To(MatchError("invalid partition 3, topic has 3 partition(s)"))
+
+		sub = file.NewSubscriberWithConfig(dir, &file.SubscriberConfig{ConsumePartitions: []int{1, 1}})
+		defer sub.Close()
+		_, err = sub.Topic("topic").Subscribe(bps.HandlerFunc(func(bps.SubMessage) {}))
+		Expect(err).To(MatchError("duplicate partition 1"))
+	})
+})
+
+var _ = Describe("Subscriber (consumer)", func() {
+	var ctx = context.Background()
+	var dir string
+
+	consume := func(consumer string, n int) []string {
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+"?consumer="+consumer)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		var res []string
+		for i := 0; i < n; i++ {
+			var data string
+			Eventually(received).Should(Receive(&data))
+			res = append(res, data)
+		}
+		Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+		return res
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+
+		seedTopic(dir, "topic", []bps.SubMessage{bps.RawSubMessage("v1"), bps.RawSubMessage("v2")})
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should resume from checkpoints", func() {
+		Expect(consume("a", 2)).To(Equal([]string{"v1", "v2"}))
+		Expect(consume("a", 0)).To(BeEmpty())
+
+		seedTopic(dir, "topic", []bps.SubMessage{bps.RawSubMessage("v3")})
+		Expect(consume("a", 1)).To(Equal([]string{"v3"}))
+		Expect(consume("b", 3)).To(Equal([]string{"v1", "v2", "v3"}))
+		Expect(consume("", 3)).To(Equal([]string{"v1", "v2", "v3"}))
+
+		Expect(filepath.Join(dir, ".checkpoints", "topic", "a")).To(BeARegularFile())
+		Expect(filepath.Join(dir, ".checkpoints", "topic", "b")).To(BeARegularFile())
+	})
+
+	It("should start over from unreadable checkpoints", func() {
+		Expect(consume("a", 2)).To(Equal([]string{"v1", "v2"}))
+
+		name := filepath.Join(dir, ".checkpoints", "topic", "a")
+		for _, data := range []string{"\x00\x00\x00\x00", "12 4\n", "-1\n"} {
+			Expect(os.WriteFile(name, []byte(data), 0666)).To(Succeed())
+			Expect(consume("a", 2)).To(Equal([]string{"v1", "v2"}))
+			Expect(consume("a", 0)).To(BeEmpty())
+		}
+	})
+
+	It("should reject invalid consumer names", func() {
+		sub, err := bps.NewSubscriber(ctx, "file://"+dir+"?consumer=..")
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		_, err = sub.Topic("topic").Subscribe(bps.HandlerFunc(func(bps.SubMessage) {}))
+		Expect(err).To(MatchError(`invalid consumer name ".."`))
+	})
+
+	It("should delete checkpoints with topics", func() {
+		Expect(consume("a", 2)).To(HaveLen(2))
+
+		admin, err := bps.NewAdmin(ctx, "file://"+dir)
+		Expect(err).NotTo(HaveOccurred())
+		defer admin.Close()
+
+		Expect(admin.DeleteTopic(ctx, "topic")).To(Succeed())
+		Expect(filepath.Join(dir, ".checkpoints", "topic")).NotTo(BeAnExistingFile())
+		Expect(admin.ListTopics(ctx)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Admin", func() {
+	var subject bps.Admin
+	var ctx = context.Background()
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+
+		subject, err = bps.NewAdmin(ctx, "file://"+dir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should list topic files", func() {
+		Expect(subject.CreateTopic(ctx, "c", nil)).To(Succeed())
+		Expect(subject.CreateTopic(ctx, "a", nil)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(dir, "b"), 0777)).To(Succeed())
+		Expect(subject.ListTopics(ctx)).To(Equal([]string{"a", "c"}))
+	})
+
+	It("should manage segmented topics", func() {
+		pub, err := file.NewPublisherWithConfig(dir, &file.PublisherConfig{SegmentBytes: 16})
+		Expect(err).NotTo(HaveOccurred())
+		for _, data := range []string{"v1", "v2", "v3"} {
+			Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte(data)})).To(Succeed())
+		}
+		Expect(pub.Close()).To(Succeed())
+		Expect(filepath.Join(dir, ".segments", "topic")).To(BeADirectory())
+
+		// topics with rolled segments only still exist:
+		Expect(os.Remove(filepath.Join(dir, "topic"))).To(Succeed())
+		Expect(subject.ListTopics(ctx)).To(Equal([]string{"topic"}))
+		Expect(subject.DescribeTopic(ctx, "topic")).To(Equal(&bps.TopicInfo{Name: "topic", TopicConfig: bps.TopicConfig{Partitions: 1}}))
+		Expect(subject.CreateTopic(ctx, "topic", nil)).To(MatchError(bps.ErrTopicExists))
+
+		Expect(subject.DeleteTopic(ctx, "topic")).To(Succeed())
+		Expect(filepath.Join(dir, ".segments", "topic")).NotTo(BeADirectory())
+		Expect(subject.ListTopics(ctx)).To(BeEmpty())
+		Expect(subject.DeleteTopic(ctx, "topic")).To(MatchError(bps.ErrTopicNotFound))
+	})
+
+	It("should manage partitioned topics", func() {
+		pub, err := file.NewPublisherWithConfig(dir, &file.PublisherConfig{Partitions: 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Topic("orders.gz").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+		Expect(pub.Close()).To(Succeed())
+		Expect(subject.CreateTopic(ctx, "foo-0", nil)).To(Succeed())
+
+		Expect(subject.ListTopics(ctx)).To(Equal([]string{"foo-0", "orders.gz"}))
+		Expect(subject.DescribeTopic(ctx, "orders.gz")).To(Equal(&bps.TopicInfo{Name: "orders.gz", TopicConfig: bps.TopicConfig{Partitions: 3}}))
+		Expect(subject.DescribeTopic(ctx, "foo-0")).To(Equal(&bps.TopicInfo{Name: "foo-0", TopicConfig: bps.TopicConfig{Partitions: 1}}))
+		Expect(subject.CreateTopic(ctx, "orders.gz", nil)).To(MatchError(bps.ErrTopicExists))
+
+		Expect(subject.DeleteTopic(ctx, "orders.gz")).To(Succeed())
+		Expect(filepath.Join(dir, "orders.gz")).NotTo(BeAnExistingFile())
+		Expect(subject.ListTopics(ctx)).To(Equal([]string{"foo-0"}))
+		Expect(subject.DeleteTopic(ctx, "orders.gz")).To(MatchError(bps.ErrTopicNotFound))
+	})
+
+	It("should create partitioned topics", func() {
+		Expect(subject.CreateTopic(ctx, "topic", &bps.TopicConfig{Partitions: 2})).To(Succeed())
+		Expect(subject.CreateTopic(ctx, "topic", &bps.TopicConfig{Partitions: 2})).To(MatchError(bps.ErrTopicExists))
+		Expect(subject.DescribeTopic(ctx, "topic")).To(Equal(&bps.TopicInfo{Name: "topic", TopicConfig: bps.TopicConfig{Partitions: 2}}))
+		Expect(filepath.Join(dir, "topic", "0")).To(BeARegularFile())
+		Expect(filepath.Join(dir, "topic", "1")).To(BeARegularFile())
+
+		// publishers and subscribers share the topic:
+		pub, err := file.NewPublisherWithConfig(dir, &file.PublisherConfig{Partitions: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+		Expect(pub.Close()).To(Succeed())
+
+		received := make(chan string, 10)
+		sub := file.NewSubscriber(dir)
+		defer sub.Close()
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			received <- string(msg.Data())
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+		Eventually(received).Should(Receive(Equal("v1")))
+	})
+
+	It("should reject reserved topic names", func() {
+		for _, name := range []string{".segments", ".checkpoints", "..", "a/b"} {
+			Expect(subject.CreateTopic(ctx, name, nil)).To(MatchError(fmt.Sprintf("invalid topic name %q", name)))
+			Expect(subject.DeleteTopic(ctx, name)).To(MatchError(fmt.Sprintf("invalid topic name %q", name)))
+		}
+	})
+
+	Context("lint", func() {
+		var shared lint.AdminInput
+
+		BeforeEach(func() {
+			shared = lint.AdminInput{Subject: subject}
+		})
+
+		lint.Admin(&shared)
+	})
+})
+
+var _ = Describe("URL options", func() {
+	lint.QueryDoc("file.go", file.QueryParams...)
+})
+
 // ------------------------------------------------------------------------
 
 func TestSuite(t *testing.T) {
@@ -0,0 +1,318 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bsm/bps"
+)
+
+// Format defines the on-disk message format.
+type Format string
+
+// Format options.
+const (
+	// FormatJSONL stores messages as JSON lines, Data is base64-encoded.
+	FormatJSONL Format = "jsonl"
+
+	// FormatRaw stores message Data as newline-delimited text,
+	// ID and Attributes are not stored, Data must not contain line breaks.
+	FormatRaw Format = "raw"
+
+	// FormatBinary stores messages as length-prefixed binary records with ID and Attributes.
+	FormatBinary Format = "binary"
+
+	// FormatCSV stores messages as CSV records: ID, Data and attribute key-value pairs.
+	FormatCSV Format = "csv"
+)
+
+var formats = []string{string(FormatJSONL), string(FormatRaw), string(FormatBinary), string(FormatCSV)}
+
+// codec encodes, splits and decodes records.
+type codec interface {
+	// Append appends an encoded message record to dst.
+	Append(dst []byte, msg *bps.PubMessage) ([]byte, error)
+	// Split splits records, like bufio.SplitFunc. It returns 0 advance, if data contains no complete record.
+	Split(data []byte, atEOF bool) (advance int, record []byte, err error)
+	// Decode decodes a record, it returns nil message for records, that must be skipped.
+	Decode(record []byte) (*bps.PubMessage, error)
+	// LineBased reports whether records are newline-terminated.
+	LineBased() bool
+}
+
+func newCodec(format Format) (codec, error) {
+	switch format {
+	case FormatJSONL, "":
+		return jsonlCodec{}, nil
+	case FormatRaw:
+		return rawCodec{}, nil
+	case FormatBinary:
+		return binaryCodec{}, nil
+	case FormatCSV:
+		return csvCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// --------------------------------------------------------------------
+
+// splitLines splits newline-terminated records, the last one may be not terminated at EOF.
+func splitLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i > -1 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) != 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// --------------------------------------------------------------------
+
+type jsonlCodec struct{}
+
+func (jsonlCodec) Append(dst []byte, msg *bps.PubMessage) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return dst, err
+	}
+	dst = append(dst, data...)
+	return append(dst, '\n'), nil
+}
+
+func (jsonlCodec) Split(data []byte, atEOF bool) (int, []byte, error) {
+	return splitLines(data, atEOF)
+}
+
+func (jsonlCodec) Decode(record []byte) (*bps.PubMessage, error) {
+	if len(bytes.TrimSpace(record)) == 0 {
+		return nil, nil
+	}
+
+	msg := new(bps.PubMessage)
+	if err := json.Unmarshal(record, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (jsonlCodec) LineBased() bool { return true }
+
+// --------------------------------------------------------------------
+
+type rawCodec struct{}
+
+func (rawCodec) Append(dst []byte, msg *bps.PubMessage) ([]byte, error) {
+	if bytes.IndexByte(msg.Data, '\n') > -1 {
+		return dst, errors.New("raw format does not support line breaks in message data")
+	}
+	dst = append(dst, msg.Data...)
+	return append(dst, '\n'), nil
+}
+
+func (rawCodec) Split(data []byte, atEOF bool) (int, []byte, error) {
+	return splitLines(data, atEOF)
+}
+
+func (rawCodec) Decode(record []byte) (*bps.PubMessage, error) {
+	record = bytes.TrimSuffix(record, []byte{'\n'})
+	return &bps.PubMessage{Data: append([]byte{}, record...)}, nil
+}
+
+func (rawCodec) LineBased() bool { return true }
+
+// --------------------------------------------------------------------
+
+// binaryCodec frames records with a 4-byte big-endian body length,
+// body contains uvarint length-prefixed ID, Data and attribute count followed by key-value pairs.
+type binaryCodec struct{}
+
+const binaryHeaderLen = 4
+
+func (binaryCodec) Append(dst []byte, msg *bps.PubMessage) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, 0, 0, 0, 0) // reserve header
+
+	dst = appendBytes(dst, []byte(msg.ID))
+	dst = appendBytes(dst, msg.Data)
+	dst = appendUvarint(dst, uint64(len(msg.Attributes)))
+	for _, key := range sortedKeys(msg.Attributes) {
+		dst = appendBytes(dst, []byte(key))
+		dst = appendBytes(dst, []byte(msg.Attributes[key]))
+	}
+
+	size := len(dst) - start - binaryHeaderLen
+	if uint64(size) > uint64(^uint32(0)) {
+		return dst[:start], errors.New("binary format does not support records larger than 4GiB")
+	}
+	binary.BigEndian.PutUint32(dst[start:], uint32(size))
+	return dst, nil
+}
+
+func (binaryCodec) Split(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) >= binaryHeaderLen {
+		if n := binaryHeaderLen + int(binary.BigEndian.Uint32(data)); len(data) >= n {
+			return n, data[binaryHeaderLen:n], nil
+		}
+	}
+	if atEOF && len(data) != 0 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return 0, nil, nil
+}
+
+func (binaryCodec) Decode(record []byte) (*bps.PubMessage, error) {
+	r := binaryReader{data: record}
+
+	msg := &bps.PubMessage{
+		ID:   string(r.Bytes()),
+		Data: append([]byte{}, r.Bytes()...),
+	}
+	if n := r.Uvarint(); n != 0 && r.err == nil {
+		msg.Attributes = make(map[string]string, n)
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			key := string(r.Bytes())
+			msg.Attributes[key] = string(r.Bytes())
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return msg, nil
+}
+
+func (binaryCodec) LineBased() bool { return false }
+
+func appendUvarint(dst []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(dst, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendBytes(dst, b []byte) []byte {
+	dst = appendUvarint(dst, uint64(len(b)))
+	return append(dst, b...)
+}
+
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) Uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.New("invalid binary record")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) Bytes() []byte {
+	n := r.Uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.data)) {
+		r.err = errors.New("invalid binary record")
+		return nil
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// --------------------------------------------------------------------
+
+type csvCodec struct{}
+
+func (csvCodec) Append(dst []byte, msg *bps.PubMessage) ([]byte, error) {
+	record := make([]string, 0, 2+2*len(msg.Attributes))
+	record = append(record, msg.ID, string(msg.Data))
+	for _, key := range sortedKeys(msg.Attributes) {
+		record = append(record, key, msg.Attributes[key])
+	}
+
+	// encoding/csv reads quoted \r\n as \n, reject values, that would not round-trip:
+	for _, field := range record {
+		if strings.Contains(field, "\r\n") {
+			return dst, errors.New("csv format does not support CRLF line breaks")
+		}
+	}
+
+	buf := bytes.NewBuffer(dst)
+	w := csv.NewWriter(buf)
+	if err := w.Write(record); err != nil {
+		return dst, err
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Split splits records by line breaks, which are not quoted.
+func (csvCodec) Split(data []byte, atEOF bool) (int, []byte, error) {
+	quoted := false
+	for i, c := range data {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case '\n':
+			if !quoted {
+				return i + 1, data[:i+1], nil
+			}
+		}
+	}
+	if atEOF && len(data) != 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func (csvCodec) Decode(record []byte) (*bps.PubMessage, error) {
+	if len(bytes.TrimSpace(record)) == 0 {
+		return nil, nil
+	}
+
+	r := csv.NewReader(bytes.NewReader(record))
+	r.FieldsPerRecord = -1
+	fields, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 || len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid csv record: %d fields", len(fields))
+	}
+
+	msg := &bps.PubMessage{ID: fields[0], Data: []byte(fields[1])}
+	if len(fields) > 2 {
+		msg.Attributes = make(map[string]string, len(fields)/2-1)
+		for i := 2; i < len(fields); i += 2 {
+			msg.Attributes[fields[i]] = fields[i+1]
+		}
+	}
+	return msg, nil
+}
+
+func (csvCodec) LineBased() bool { return true }
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
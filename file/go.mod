@@ -6,6 +6,7 @@ require (
 	github.com/bsm/bps v0.2.4
 	github.com/bsm/ginkgo v1.16.5
 	github.com/bsm/gomega v1.17.0
+	github.com/klauspost/compress v1.15.14
 )
 
 require github.com/google/uuid v1.3.0 // indirect
@@ -0,0 +1,12 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package file
+
+import "os"
+
+// lockFile is a no-op, file locking is not supported on this platform.
+func lockFile(*os.File) error { return nil }
+
+// unlockFile is a no-op, file locking is not supported on this platform.
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,74 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package file_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/bsm/bps"
+	. "github.com/bsm/ginkgo"
+	. "github.com/bsm/gomega"
+)
+
+var _ = Describe("Publisher (locking)", func() {
+	var subject bps.Publisher
+	var ctx = context.Background()
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bps-file-test")
+		Expect(err).NotTo(HaveOccurred())
+
+		subject, err = bps.NewPublisher(ctx, "file://"+dir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should wait for locks held by other processes", func() {
+		Expect(subject.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+
+		f, err := os.OpenFile(filepath.Join(dir, "topic"), os.O_APPEND|os.O_WRONLY, 0666)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		Expect(syscall.Flock(int(f.Fd()), syscall.LOCK_EX)).To(Succeed())
+
+		published := make(chan error, 1)
+		go func() {
+			published <- subject.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})
+		}()
+		Consistently(published, 50*time.Millisecond).ShouldNot(Receive())
+
+		_, err = f.Write([]byte(`{"data":"djM="}` + "\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(syscall.Flock(int(f.Fd()), syscall.LOCK_UN)).To(Succeed())
+		Eventually(published).Should(Receive(BeNil()))
+
+		Expect(os.ReadFile(filepath.Join(dir, "topic"))).To(Equal([]byte(`{"data":"djE="}` + "\n" +
+			`{"data":"djM="}` + "\n" +
+			`{"data":"djI="}` + "\n")))
+	})
+
+	It("should reopen segments, rolled by other processes", func() {
+		Expect(subject.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+
+		segments := filepath.Join(dir, ".segments", "topic")
+		Expect(os.MkdirAll(segments, 0777)).To(Succeed())
+		Expect(os.Rename(filepath.Join(dir, "topic"), filepath.Join(segments, "00000000000000000000"))).To(Succeed())
+
+		Expect(subject.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(segments, "00000000000000000000"))).To(Equal([]byte(`{"data":"djE="}` + "\n")))
+		Expect(os.ReadFile(filepath.Join(dir, "topic"))).To(Equal([]byte(`{"data":"djI="}` + "\n")))
+	})
+})
@@ -0,0 +1,623 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// segmentDir is the root sub-directory, that contains rolled topic segments.
+const segmentDir = ".segments"
+
+// partitionsFile is the file within partitioned topic directories, that stores the number of partitions.
+const partitionsFile = ".partitions"
+
+// topicFiles resolves topic file paths.
+//
+// The active segment of a topic is stored in the root directory,
+// rolled segments are named by their base (topic) offsets and stored in segmentDir.
+// Partitioned topics are directories, that contain partitionsFile and the files of each partition,
+// laid out like topics within a root directory.
+type topicFiles struct {
+	root string
+	name string
+}
+
+func topicFilesFor(path string) topicFiles {
+	return topicFiles{root: filepath.Dir(path), name: filepath.Base(path)}
+}
+
+// Partition returns files of a topic partition.
+func (t topicFiles) Partition(partition int) topicFiles {
+	return topicFiles{root: t.Active(), name: strconv.Itoa(partition)}
+}
+
+// Active returns a path to the active segment.
+func (t topicFiles) Active() string {
+	return filepath.Join(t.root, t.name)
+}
+
+// SegmentDir returns a path to the directory of rolled segments.
+func (t topicFiles) SegmentDir() string {
+	return filepath.Join(t.root, segmentDir, t.name)
+}
+
+// Segment returns a path to a rolled segment.
+func (t topicFiles) Segment(base int64) string {
+	return filepath.Join(t.SegmentDir(), fmt.Sprintf("%020d", base))
+}
+
+// Checkpoint returns a path to a consumer checkpoint file.
+func (t topicFiles) Checkpoint(consumer string) string {
+	return filepath.Join(t.root, checkpointDir, t.name, consumer)
+}
+
+// Exists reports whether the topic exists, i.e. has an active or rolled segments or is partitioned.
+func (t topicFiles) Exists() (bool, error) {
+	if _, err := t.Partitions(); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Partitions returns the number of partitions, stored within a partitioned topic directory,
+// or 0 if the topic is not partitioned. It returns an os.IsNotExist error, if the topic does not exist.
+func (t topicFiles) Partitions() (int, error) {
+	fi, err := os.Stat(t.Active())
+	if os.IsNotExist(err) {
+		// topics may only have rolled segments:
+		if ok, e := dirExists(t.SegmentDir()); e != nil {
+			return 0, e
+		} else if ok {
+			return 0, nil
+		}
+		return 0, err
+	} else if err != nil {
+		return 0, err
+	} else if !fi.IsDir() {
+		return 0, nil
+	}
+
+	// directories without partitionsFile are not (yet) topics:
+	data, err := os.ReadFile(filepath.Join(t.Active(), partitionsFile))
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("topic %s has invalid partitions metadata %q", t.name, data)
+	}
+	return n, nil
+}
+
+// CreatePartitions creates a partitioned topic directory with n partitions, unless it exists.
+// It fails, if the topic exists with a different number of partitions or is not partitioned.
+func (t topicFiles) CreatePartitions(n int) error {
+	// check existing topics:
+	if m, err := t.Partitions(); err == nil {
+		return checkPartitions(t.name, m, n)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Mkdir(t.Active(), 0777); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	// write metadata to a temporary file and link it, so it is created atomically and only once:
+	tmp, err := os.CreateTemp(t.Active(), partitionsFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strconv.Itoa(n) + "\n"); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Link(tmp.Name(), filepath.Join(t.Active(), partitionsFile)); os.IsExist(err) {
+		m, err := t.Partitions()
+		if err != nil {
+			return err
+		}
+		return checkPartitions(t.name, m, n) // created concurrently
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkPartitions checks the number of partitions of an existing topic.
+func checkPartitions(name string, actual, expected int) error {
+	if actual == 0 {
+		return fmt.Errorf("topic %s is not partitioned, %d partitions are configured", name, expected)
+	} else if actual != expected {
+		return fmt.Errorf("topic %s has %d partition(s), %d are configured", name, actual, expected)
+	}
+	return nil
+}
+
+// Segments returns rolled segments, sorted by base offset.
+func (t topicFiles) Segments() ([]segment, error) {
+	entries, err := os.ReadDir(t.SegmentDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	segs := make([]segment, 0, len(entries))
+	for _, entry := range entries {
+		base, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || !entry.Type().IsRegular() {
+			continue
+		}
+
+		fi, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue // removed concurrently
+		} else if err != nil {
+			return nil, err
+		}
+
+		segs = append(segs, segment{
+			Path:    filepath.Join(t.SegmentDir(), entry.Name()),
+			Base:    base,
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+		})
+	}
+
+	sort.Slice(segs, func(i, j int) bool { return segs[i].Base < segs[j].Base })
+	return segs, nil
+}
+
+// segment is a rolled topic segment.
+type segment struct {
+	Path    string
+	Base    int64
+	Size    int64
+	ModTime time.Time
+}
+
+// activeBase returns the base offset of the active segment.
+func activeBase(segs []segment) int64 {
+	if n := len(segs); n != 0 {
+		return segs[n-1].Base + segs[n-1].Size
+	}
+	return 0
+}
+
+// dirExists reports whether a directory exists.
+func dirExists(name string) (bool, error) {
+	fi, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return fi.IsDir(), nil
+}
+
+// --------------------------------------------------------------------
+
+// topicReader reads complete records across topic segments, which may still be written to.
+// Compression is detected per file, offsets of compressed files refer to frames,
+// the offset is advanced past a frame once all its records are read.
+type topicReader struct {
+	files topicFiles
+	codec codec
+
+	file    *os.File
+	base    int64  // base offset of the current file
+	rolled  bool   // current file is a rolled segment, nothing will be appended to it
+	mem     []byte // read buffer
+	buf     []byte // unconsumed (partially read) data within mem
+	discard bool   // discard the next record
+	offset  int64  // offset of the next record
+
+	detected     bool         // compression of the current file is detected
+	compression  Compression  // compression of the last detected file
+	decompressor decompressor // nil, unless the current file is compressed
+	dmem         []byte       // decompression buffer
+	data         []byte       // unconsumed decompressed data of the current frame within dmem
+	next         int64        // offset of the next frame
+}
+
+func newTopicReader(files topicFiles, codec codec) *topicReader {
+	return &topicReader{files: files, codec: codec}
+}
+
+// SeekOldest positions reader at the oldest available message.
+func (r *topicReader) SeekOldest() error {
+	return r.open(-1)
+}
+
+// SeekNewest positions reader at the end of topic.
+// A partially written last record is skipped, if records are newline-terminated.
+// Compressed active segments are decompressed to find the end of the last complete frame.
+func (r *topicReader) SeekNewest() error {
+	segs, err := r.files.Segments()
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(r.files.Active())
+	if err != nil {
+		return err
+	}
+	if err := r.open(activeBase(segs) + fi.Size()); err != nil {
+		return err
+	}
+
+	if err := r.detect(); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if r.decompressor != nil {
+		return r.skipFrames()
+	}
+
+	if pos := r.offset - r.base; pos != 0 && r.codec.LineBased() {
+		last := make([]byte, 1)
+		if _, err := r.file.ReadAt(last, pos-1); err != nil {
+			return err
+		}
+		r.discard = last[0] != '\n'
+	}
+	return nil
+}
+
+// SeekTo positions reader at the offset, which must be a record start.
+// Offsets of removed segments are moved to the oldest available message,
+// offsets beyond the end of topic are reset to the oldest available message (topic was re-created).
+func (r *topicReader) SeekTo(offset int64) error {
+	return r.open(offset)
+}
+
+// Offset returns the offset of the next record.
+func (r *topicReader) Offset() int64 {
+	return r.offset
+}
+
+// ReadRecord returns the next complete record, which is valid till the next call.
+// It returns io.EOF when there are no more complete records (yet),
+// the remaining partial record is kept and can be retrieved by ReadPending.
+func (r *topicReader) ReadRecord() ([]byte, error) {
+	for {
+		record, err := r.readRecord()
+		if err != io.EOF {
+			return record, err
+		}
+
+		if !r.rolled {
+			if r.rolled, err = r.isRotated(); err != nil {
+				return nil, err
+			} else if r.rolled {
+				continue // read records, appended before rotation
+			}
+			return nil, io.EOF
+		}
+
+		// rolled segments may end with a partial record only after a crash,
+		// truncated records are skipped:
+		if record, _ := r.ReadPending(); record != nil {
+			return record, nil
+		}
+
+		// continue with the next segment:
+		if err := r.open(r.offset); os.IsNotExist(err) {
+			return nil, io.EOF // next segment is not created yet
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadPending returns the partial record, that is not terminated yet, as the last one.
+// Pending data is consumed, even if it is not a valid record.
+func (r *topicReader) ReadPending() ([]byte, error) {
+	if len(r.buf) == 0 {
+		return nil, nil
+	}
+
+	if r.decompressor != nil {
+		// incomplete frames cannot be decompressed, they are skipped:
+		r.offset += int64(len(r.buf))
+		r.buf = r.buf[:0]
+		return nil, nil
+	}
+
+	n, record, err := r.codec.Split(r.buf, true)
+	if err != nil || n == 0 {
+		r.offset += int64(len(r.buf))
+		r.buf = r.buf[:0]
+		r.discard = false
+		return nil, err
+	}
+
+	r.buf = r.buf[n:]
+	r.offset += int64(n)
+	if r.discard {
+		r.discard = false
+		return nil, nil
+	}
+	return record, nil
+}
+
+// Close closes the current file.
+func (r *topicReader) Close() (err error) {
+	if r.decompressor != nil {
+		err = r.decompressor.Close()
+	}
+	if r.file != nil {
+		if e := r.file.Close(); e != nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (r *topicReader) readRecord() ([]byte, error) {
+	if err := r.detect(); err != nil {
+		return nil, err
+	}
+	if r.decompressor != nil {
+		return r.readFrameRecord()
+	}
+
+	for {
+		if len(r.buf) != 0 {
+			n, record, err := r.codec.Split(r.buf, false)
+			if err != nil {
+				return nil, err
+			}
+			if n != 0 {
+				r.buf = r.buf[n:]
+				r.offset += int64(n)
+
+				if r.discard {
+					r.discard = false
+					continue
+				}
+				return record, nil
+			}
+		}
+
+		if err := r.fill(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readFrameRecord returns the next record of a compressed file.
+func (r *topicReader) readFrameRecord() ([]byte, error) {
+	for {
+		if len(r.data) != 0 {
+			// frames contain complete records only:
+			n, record, err := r.codec.Split(r.data, true)
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				n = len(r.data)
+			}
+
+			r.data = r.data[n:]
+			if len(r.data) == 0 {
+				r.offset = r.next
+			}
+			if record != nil {
+				return record, nil
+			}
+			continue
+		}
+
+		n, data, err := r.decompressor.Decompress(r.dmem[:0], r.buf)
+		if err != nil {
+			return nil, err
+		}
+		if n != 0 {
+			r.buf = r.buf[n:]
+			r.dmem, r.data = data, data
+			r.next = r.offset + int64(n)
+			if len(r.data) == 0 {
+				r.offset = r.next
+			}
+			continue
+		}
+
+		if err := r.fill(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// skipFrames positions reader after the last complete frame of the current file.
+func (r *topicReader) skipFrames() error {
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.offset = r.base
+	r.buf = r.buf[:0]
+
+	for {
+		n, data, err := r.decompressor.Decompress(r.dmem[:0], r.buf)
+		if err != nil {
+			return err
+		}
+		if n != 0 {
+			r.buf = r.buf[n:]
+			r.dmem = data
+			r.offset += int64(n)
+			continue
+		}
+
+		if err := r.fill(); err == io.EOF {
+			r.next = r.offset
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// detect detects compression of the current file by its header.
+// It returns io.EOF, if the active file is too short to decide.
+func (r *topicReader) detect() error {
+	if r.detected {
+		return nil
+	}
+
+	head := make([]byte, len(zstdMagic))
+	n, err := r.file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	compression, ok := sniffCompression(head[:n])
+	if !ok {
+		if !r.rolled {
+			return io.EOF
+		}
+		compression = CompressionNone
+	}
+
+	if compression != r.compression {
+		if r.decompressor != nil {
+			_ = r.decompressor.Close()
+		}
+		if r.decompressor, err = newDecompressor(compression); err != nil {
+			return err
+		}
+		r.compression = compression
+	}
+	r.detected = true
+	return nil
+}
+
+// fill reads more data from the current file.
+func (r *topicReader) fill() error {
+	if len(r.buf) == cap(r.mem) {
+		// grow buffer for large records:
+		mem := make([]byte, 2*cap(r.mem)+64*1024)
+		r.buf = mem[:copy(mem, r.buf)]
+		r.mem = mem
+	} else {
+		// move unconsumed data to the front:
+		r.mem = r.mem[:cap(r.mem)]
+		r.buf = r.mem[:copy(r.mem, r.buf)]
+	}
+
+	n, err := r.file.Read(r.mem[len(r.buf):])
+	r.buf = r.mem[:len(r.buf)+n]
+	if n != 0 {
+		return nil
+	} else if err == nil {
+		return io.EOF
+	}
+	return err
+}
+
+// isRotated reports whether the current (active) file was rolled or removed.
+func (r *topicReader) isRotated() (bool, error) {
+	cur, err := r.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	fi, err := os.Stat(r.files.Active())
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !os.SameFile(cur, fi), nil
+}
+
+// open opens the file, containing the offset (or the oldest one, if negative) and seeks to it.
+func (r *topicReader) open(offset int64) error {
+	const maxAttempts = 5
+
+	for attempt := 1; ; attempt++ {
+		segs, err := r.files.Segments()
+		if err != nil {
+			return err
+		}
+
+		oldest := activeBase(segs)
+		if len(segs) != 0 {
+			oldest = segs[0].Base
+		}
+		if offset < oldest {
+			offset = oldest
+		}
+
+		name, base, rolled := r.files.Active(), activeBase(segs), false
+		if offset < base {
+			seg := segs[sort.Search(len(segs), func(i int) bool { return segs[i].Base > offset })-1]
+			name, base, rolled = seg.Path, seg.Base, true
+		}
+
+		file, err := os.Open(name)
+		if os.IsNotExist(err) && attempt < maxAttempts {
+			continue // rolled or removed concurrently
+		} else if err != nil {
+			return err
+		}
+
+		if !rolled {
+			// ensure active segment was not rolled concurrently:
+			if segs, err = r.files.Segments(); err != nil {
+				_ = file.Close()
+				return err
+			}
+			if activeBase(segs) != base && attempt < maxAttempts {
+				_ = file.Close()
+				continue
+			}
+		}
+
+		fi, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		if offset-base > fi.Size() && attempt < maxAttempts {
+			// topic was re-created, start over:
+			_ = file.Close()
+			offset = -1
+			continue
+		}
+
+		if _, err := file.Seek(offset-base, io.SeekStart); err != nil {
+			_ = file.Close()
+			return err
+		}
+
+		if r.file != nil {
+			_ = r.file.Close()
+		}
+		r.file = file
+		r.base = base
+		r.rolled = rolled
+		r.offset = offset
+		r.buf = r.buf[:0]
+		r.discard = false
+		r.detected = false
+		r.data = nil
+		r.next = offset
+		return nil
+	}
+}
@@ -0,0 +1,225 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bsm/bps"
+)
+
+// FsyncPolicy defines when published messages are synced to disk.
+type FsyncPolicy string
+
+// FsyncPolicy options.
+const (
+	// FsyncAlways syncs after every message, before Publish returns.
+	FsyncAlways FsyncPolicy = "always"
+
+	// FsyncInterval syncs periodically in background, Publish returns once a message is synced
+	// by the next periodic sync and reports its error. Publishes within an interval share a single sync,
+	// at the cost of up to FsyncInterval latency.
+	FsyncInterval FsyncPolicy = "interval"
+
+	// FsyncGroup syncs after every message, before Publish returns,
+	// but concurrent publishes share a single sync (group commit).
+	FsyncGroup FsyncPolicy = "group"
+)
+
+// PublisherConfig contains publisher settings.
+type PublisherConfig struct {
+	// SegmentBytes is the max size of a topic segment file, before it is rolled.
+	// Default: 0 (unlimited).
+	SegmentBytes int
+	// SegmentAge is the max age of a topic segment file, before it is rolled.
+	// Age is measured from the time a segment was opened for writing, it is checked on publish.
+	// Default: 0 (unlimited).
+	SegmentAge time.Duration
+	// RetentionBytes is the max total size of rolled segments, oldest segments are removed first.
+	// Default: 0 (unlimited).
+	RetentionBytes int
+	// RetentionAge is the max age (since the last write) of rolled segments.
+	// Default: 0 (unlimited).
+	RetentionAge time.Duration
+	// ArchiveDir is an optional directory to move segments to, instead of deleting them.
+	// Default: "" (delete segments).
+	ArchiveDir string
+	// Fsync defines when published messages are synced to disk.
+	// Default: FsyncAlways.
+	Fsync FsyncPolicy
+	// FsyncInterval is the sync interval for FsyncInterval policy.
+	// Default: 100ms.
+	FsyncInterval time.Duration
+	// Format is the on-disk message format.
+	// Default: FormatJSONL.
+	Format Format
+	// Compression is the topic file compression. Messages are compressed in batches, a batch is
+	// written as a self-contained gzip member or zstd frame, whenever messages are synced.
+	// Default: "" (inferred from the topic name extension: .gz for gzip, .zst for zstd, none otherwise).
+	Compression Compression
+	// Partitions is the number of partition files per topic, stored within a <topic> directory.
+	// Messages are assigned to partitions by the hash of their IDs (like the kafka hash partitioner),
+	// messages without IDs are distributed round-robin.
+	// Default: 0 (single topic file).
+	Partitions int
+}
+
+func (c *PublisherConfig) norm() *PublisherConfig {
+	var n PublisherConfig
+	if c != nil {
+		n = *c
+	}
+	if n.Fsync == "" {
+		n.Fsync = FsyncAlways
+	}
+	if n.FsyncInterval <= 0 {
+		n.FsyncInterval = 100 * time.Millisecond
+	}
+	return &n
+}
+
+// params declares publisher parameters.
+func (c *PublisherConfig) params(params *bps.QueryParams) {
+	params.Int(&c.SegmentBytes, "segment_bytes", "Max size of a topic segment file, before it is rolled (default unlimited).")
+	params.Duration(&c.SegmentAge, "segment_age", "Max age of a topic segment file, before it is rolled (default unlimited).")
+	params.Int(&c.RetentionBytes, "retention_bytes", "Max total size of rolled segments, oldest are removed first (default unlimited).")
+	params.Duration(&c.RetentionAge, "retention_age", "Max age (since the last write) of rolled segments (default unlimited).")
+	params.String(&c.ArchiveDir, "archive_dir", "Directory to move segments to, instead of deleting them (default none).")
+	params.Enum("fsync", "When to sync messages to disk. Valid values are: always (default, after every message),\n"+
+		"interval (periodically, publishes wait for the next sync), group (after every message, concurrent\n"+
+		"publishes share a sync).",
+		[]string{string(FsyncAlways), string(FsyncInterval), string(FsyncGroup)}, func(v string) {
+			c.Fsync = FsyncPolicy(v)
+		})
+	params.Duration(&c.FsyncInterval, "fsync_interval", "Sync interval for fsync=interval (default 100ms).")
+	params.Enum("format", formatUsage, formats, func(v string) { c.Format = Format(v) })
+	params.Enum("compression", "Topic file compression. Valid values are: none, gzip, zstd\n"+
+		"(default inferred from the topic name extension: .gz for gzip, .zst for zstd, none otherwise).",
+		compressions, func(v string) { c.Compression = Compression(v) })
+	params.Int(&c.Partitions, "partitions", "Number of partition files per topic, stored within a topic directory, messages are\n"+
+		"assigned by the hash of their IDs (default 0, a single topic file).")
+}
+
+// --------------------------------------------------------------------
+
+// SubscriberConfig contains subscriber settings.
+type SubscriberConfig struct {
+	// Follow keeps reading topic files as they are appended to (like `tail -f`),
+	// subscriptions wait for missing topic files to be created.
+	// Default: false (stop at the end of file).
+	Follow bool
+	// PollInterval is the interval to check topic files for new messages in Follow mode.
+	// Default: 100ms.
+	PollInterval time.Duration
+	// Consumer is an optional consumer name. Named consumers store offsets of handled messages
+	// in checkpoint files and resume from them, each consumer reads topics independently.
+	// Delivery is at-least-once: offsets are stored after messages are handled, compressed topics
+	// store offsets once per batch, so messages of a partially handled batch are re-delivered too.
+	// Default: "" (no checkpoints).
+	Consumer string
+	// Format is the on-disk message format, it must match the publisher one.
+	// Default: FormatJSONL.
+	Format Format
+	// ConsumePartitions limits partitions to consume, the number of partitions is read from the topic.
+	// Default: nil (all partitions).
+	ConsumePartitions []int
+}
+
+func (c *SubscriberConfig) norm() *SubscriberConfig {
+	var n SubscriberConfig
+	if c != nil {
+		n = *c
+	}
+	if n.PollInterval <= 0 {
+		n.PollInterval = 100 * time.Millisecond
+	}
+	return &n
+}
+
+// params declares subscriber parameters.
+func (c *SubscriberConfig) params(params *bps.QueryParams) {
+	params.Bool(&c.Follow, "follow", "Keep reading topic files as they are appended to (default false).")
+	params.Duration(&c.PollInterval, "poll_interval", "Interval to check topic files for new messages in follow mode (default 100ms).")
+	params.String(&c.Consumer, "consumer", consumerUsage)
+	params.Enum("format", formatUsage, formats, func(v string) { c.Format = Format(v) })
+	params.Func("consume_partitions", "Comma-separated partitions to consume (default all).", func(v string) error {
+		c.ConsumePartitions = c.ConsumePartitions[:0]
+		for _, s := range strings.Split(v, ",") {
+			num, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return errors.New("not a list of integers")
+			}
+			c.ConsumePartitions = append(c.ConsumePartitions, num)
+		}
+		return nil
+	})
+}
+
+const consumerUsage = "Consumer name to store and resume offsets of handled messages (default none). Delivery is\n" +
+	"at-least-once: messages, handled since the last stored offset, are re-delivered after a crash, compressed\n" +
+	"topics re-deliver the rest of a batch too."
+
+const formatUsage = "On-disk message format. Valid values are: jsonl (default, JSON lines), raw (newline-delimited\n" +
+	"message data only), binary (length-prefixed records), csv (ID, data and attribute key-value pairs,\n" +
+	"without CRLF line breaks)."
+
+// checkpointDir is the root sub-directory, that contains checkpoint files.
+const checkpointDir = ".checkpoints"
+
+// --------------------------------------------------------------------
+
+// checkpoint stores a consumer offset.
+type checkpoint struct {
+	file *os.File
+	buf  []byte
+}
+
+// openCheckpoint opens a checkpoint file, returns the stored offset or -1 if there's none.
+// Checkpoints are not synced, unparsable ones (torn or zeroed by a crash) are treated as missing.
+func openCheckpoint(name string) (*checkpoint, int64, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return nil, 0, err
+	}
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, err
+	}
+
+	offset := int64(-1)
+	if data = bytes.TrimSpace(data); len(data) != 0 {
+		if n, err := strconv.ParseInt(string(data), 10, 64); err == nil && n > -1 {
+			offset = n
+		}
+	}
+	return &checkpoint{file: f}, offset, nil
+}
+
+// Commit stores the offset.
+// Offsets are written with a fixed width, so a single write replaces the previous one.
+func (c *checkpoint) Commit(offset int64) error {
+	c.buf = strconv.AppendInt(c.buf[:0], offset, 10)
+	for len(c.buf) < 19 {
+		c.buf = append(c.buf, ' ')
+	}
+	c.buf = append(c.buf, '\n')
+
+	_, err := c.file.WriteAt(c.buf, 0)
+	return err
+}
+
+// Close closes the checkpoint file.
+func (c *checkpoint) Close() error {
+	return c.file.Close()
+}
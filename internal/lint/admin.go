@@ -0,0 +1,49 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bsm/bps"
+	"github.com/bsm/ginkgo"
+	Ω "github.com/bsm/gomega"
+)
+
+// AdminInput for the shared test.
+type AdminInput struct {
+	Subject bps.Admin
+}
+
+// Admin lints topic management.
+func Admin(input *AdminInput) {
+	var ctx = context.Background()
+
+	ginkgo.It("should manage topics", func() {
+		name := fmt.Sprintf("bps-unittest-admin-%d", time.Now().UnixNano())
+		subject := input.Subject
+
+		_, err := subject.DescribeTopic(ctx, name)
+		Ω.Expect(err).To(Ω.MatchError(bps.ErrTopicNotFound))
+		Ω.Expect(subject.DeleteTopic(ctx, name)).To(Ω.MatchError(bps.ErrTopicNotFound))
+
+		Ω.Expect(subject.CreateTopic(ctx, name, &bps.TopicConfig{Partitions: 1})).To(Ω.Succeed())
+		Ω.Eventually(func() error {
+			return subject.CreateTopic(ctx, name, nil)
+		}, 3*subscriptionWaitDelay).Should(Ω.MatchError(bps.ErrTopicExists))
+
+		Ω.Eventually(func() ([]string, error) {
+			return subject.ListTopics(ctx)
+		}, 3*subscriptionWaitDelay).Should(Ω.ContainElement(name))
+
+		info, err := subject.DescribeTopic(ctx, name)
+		Ω.Expect(err).NotTo(Ω.HaveOccurred())
+		Ω.Expect(info.Name).To(Ω.Equal(name))
+		Ω.Expect(info.Partitions).To(Ω.BeNumerically("<=", 1))
+
+		Ω.Expect(subject.DeleteTopic(ctx, name)).To(Ω.Succeed())
+		Ω.Eventually(func() ([]string, error) {
+			return subject.ListTopics(ctx)
+		}, 3*subscriptionWaitDelay).ShouldNot(Ω.ContainElement(name))
+	})
+}
@@ -0,0 +1,27 @@
+package lint
+
+import (
+	"go/parser"
+	"go/token"
+
+	"github.com/bsm/bps"
+	"github.com/bsm/ginkgo"
+	Ω "github.com/bsm/gomega"
+)
+
+// QueryDoc lints URL query parameter docs.
+// The package doc of the given source file must contain the docs of each set of declared parameters.
+func QueryDoc(file string, declare ...func(*bps.QueryParams)) {
+	ginkgo.It("should document URL query parameters", func() {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly|parser.ParseComments)
+		Ω.Expect(err).NotTo(Ω.HaveOccurred())
+		Ω.Expect(f.Doc).NotTo(Ω.BeNil())
+
+		doc := f.Doc.Text()
+		for _, fn := range declare {
+			var params bps.QueryParams
+			fn(&params)
+			Ω.Expect(doc).To(Ω.ContainSubstring(params.Doc()))
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bsm/bps"
+)
+
+// Admin wraps a kafka cluster admin and implements the bps.Admin interface.
+type Admin struct {
+	admin sarama.ClusterAdmin
+}
+
+// NewAdmin inits a new admin.
+func NewAdmin(addrs []string, config *sarama.Config) (*Admin, error) {
+	admin, err := sarama.NewClusterAdmin(addrs, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Admin{admin: admin}, nil
+}
+
+// CreateTopic implements the bps.Admin interface.
+// Partitions and replication factor default to 1, retention defaults to the broker's setting.
+func (a *Admin) CreateTopic(_ context.Context, name string, config *bps.TopicConfig) error {
+	detail := &sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}
+	if config != nil {
+		if config.Partitions > 0 {
+			detail.NumPartitions = int32(config.Partitions)
+		}
+		if config.ReplicationFactor > 0 {
+			detail.ReplicationFactor = int16(config.ReplicationFactor)
+		}
+		if config.Retention > 0 {
+			retention := strconv.FormatInt(config.Retention.Milliseconds(), 10)
+			detail.ConfigEntries = map[string]*string{"retention.ms": &retention}
+		}
+	}
+
+	err := a.admin.CreateTopic(name, detail, false)
+	if errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return bps.ErrTopicExists
+	}
+	return err
+}
+
+// DeleteTopic implements the bps.Admin interface.
+func (a *Admin) DeleteTopic(_ context.Context, name string) error {
+	err := a.admin.DeleteTopic(name)
+	if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+		return bps.ErrTopicNotFound
+	}
+	return err
+}
+
+// ListTopics implements the bps.Admin interface.
+func (a *Admin) ListTopics(_ context.Context) ([]string, error) {
+	topics, err := a.admin.ListTopics()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DescribeTopic implements the bps.Admin interface.
+func (a *Admin) DescribeTopic(_ context.Context, name string) (*bps.TopicInfo, error) {
+	metadata, err := a.admin.DescribeTopics([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata) == 0 || errors.Is(metadata[0].Err, sarama.ErrUnknownTopicOrPartition) {
+		return nil, bps.ErrTopicNotFound
+	} else if metadata[0].Err != sarama.ErrNoError {
+		return nil, metadata[0].Err
+	}
+
+	info := &bps.TopicInfo{Name: name}
+	info.Partitions = len(metadata[0].Partitions)
+	if info.Partitions != 0 {
+		info.ReplicationFactor = len(metadata[0].Partitions[0].Replicas)
+	}
+
+	entries, err := a.admin.DescribeConfig(sarama.ConfigResource{
+		Type:        sarama.TopicResource,
+		Name:        name,
+		ConfigNames: []string{"retention.ms"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Name != "retention.ms" {
+			continue
+		}
+		if ms, err := strconv.ParseInt(entry.Value, 10, 64); err == nil && ms > 0 {
+			info.Retention = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return info, nil
+}
+
+// Close implements the bps.Admin interface.
+func (a *Admin) Close() error {
+	return a.admin.Close()
+}
+
+// ClusterAdmin exposes the native cluster admin. Use at your own risk!
+func (a *Admin) ClusterAdmin() sarama.ClusterAdmin {
+	return a.admin
+}
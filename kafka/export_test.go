@@ -0,0 +1,10 @@
+package kafka
+
+import "github.com/bsm/bps"
+
+// QueryParams declares URL query parameters, documented in the package doc.
+var QueryParams = []func(*bps.QueryParams){
+	newURLConfig().commonParams,
+	newURLConfig().producerParams,
+	newURLConfig().consumerParams,
+}
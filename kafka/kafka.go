@@ -1,77 +1,152 @@
 // Package kafka provides a Kafka abstraction through github.com/Shopify/sarama.
 //
 // WARNING: there's no message ack-ing done by Subscriber, so no automatic resuming from last-processed message.
-// Subscribing is done only from oldest-known or newest or manually-specified numeric offset.
+// Subscribing is done only from oldest-known or newest offset. Use GroupSubscriber (the group.id query parameter)
+// to consume topics as a member of a consumer group and resume from committed offsets.
 //
-// Both bps.NewPublisher (`kafka` + `kafka+sync` schemes) and bps.NewConsumer (`kafka` scheme)
-// support the following query parameters:
+// All of bps.NewPublisher (`kafka` + `kafka+sync` schemes), bps.NewSubscriber and bps.NewAdmin
+// (`kafka` scheme) support the following query parameters:
 //
 //   client.id
-//      A user-provided string sent with every request to the brokers for logging debugging, and auditing
-//      purposes.
+//     A user-provided string sent with every request to the brokers for logging debugging, and auditing
+//     purposes.
 //   rack.id
-//      A rack identifier for this client. This can be any string value which indicates where this client
-//      is physically located. It corresponds with the broker config 'broker.rack'.
+//     A rack identifier for this client. This can be any string value which indicates where this client
+//     is physically located. It corresponds with the broker config 'broker.rack'.
 //   net.max.requests
-//      How many outstanding requests a connection is allowed to have before sending on it blocks (default 5).
+//     How many outstanding requests a connection is allowed to have before sending on it blocks (default 5).
 //   net.dial.timeout
-//      How long to wait for the initial connection (default 30s).
+//     How long to wait for the initial connection (default 30s).
 //   net.read.timeout
-//      How long to wait for a response (default 30s).
+//     How long to wait for a response (default 30s).
 //   net.write.timeout
-//      How long to wait for for a transmit (default 30s).
+//     How long to wait for a transmit (default 30s).
 //   net.tls.enable
-//      Whether or not to use TLS when connecting to the broker (defaults to false).
+//     Whether or not to use TLS when connecting to the broker (defaults to false).
+//   tls.ca
+//     Path to a PEM-encoded CA bundle, used to verify brokers. Enables TLS.
+//   tls.cert
+//     Path to a PEM-encoded client certificate, requires tls.key. Enables TLS.
+//   tls.key
+//     Path to a PEM-encoded client key, requires tls.cert. Enables TLS.
+//   tls.insecure
+//     Skip broker certificate verification (defaults to false). Enables TLS.
+//   sasl.mechanism
+//     Enables SASL authentication with the given mechanism. Valid values are: PLAIN, SCRAM-SHA-256,
+//     SCRAM-SHA-512.
+//   sasl.username
+//     SASL authentication username.
+//   sasl.password
+//     SASL authentication password.
 //   kafka.version
-//      The version of the kafka server.
+//     The version of the kafka server.
 //   channel.buffer.size
-//      The number of events to buffer in internal and external channels. This permits the producer to
-//      continue processing some messages in the background while user code is working, greatly improving
-//      throughput (default 256).
+//     The number of events to buffer in internal and external channels. This permits the producer to
+//     continue processing some messages in the background while user code is working, greatly improving
+//     throughput (default 256).
+//   metadata.refresh.frequency
+//     How frequently to refresh cluster metadata in the background (default 10m). Subscriptions
+//     check for new partitions at the same interval. Set to 0 to disable.
 //
 // bps.NewPublisher supports `kafka` + `kafka+sync` schemes and the following query parameters:
 //
 //   acks
-//      The number of acks required before considering a request complete. When acks=0, the producer will
-//      not wait for any acknowledgment. When acks=1 the leader will write the record to its local log but
-//      will respond without awaiting full acknowledgement from all followers. When acks=all the leader will
-//      wait for the full set of in-sync replicas to acknowledge the record.
+//     The number of acks required before considering a request complete. When acks=0, the producer will
+//     not wait for any acknowledgment. When acks=1 (default) the leader will write the record to its local log but
+//     will respond without awaiting full acknowledgement from all followers. When acks=all the leader will
+//     wait for the full set of in-sync replicas to acknowledge the record.
 //   message.max.bytes
-//      The maximum permitted size of a message (default 1,000,000). Should be
-//      set equal to or smaller than the broker's `message.max.bytes`.
+//     The maximum permitted size of a message (default 1,000,000). Should be
+//     set equal to or smaller than the broker's `message.max.bytes`.
 //   compression.type
-//      The compression type for all data generated by the producer. Valid values are: none, gzip, snappy,
-//      lz4 (default none).
+//     The compression type for all data generated by the producer. Valid values are: none, gzip, snappy,
+//     lz4, zstd (default none). zstd requires kafka.version >= 2.1.0.
+//   compression.level
+//     The codec-specific compression level, supported by gzip, lz4 and zstd compression types
+//     (defaults to the codec's default level).
+//   enable.idempotence
+//     Whether to ensure that exactly one copy of each message is written (defaults to false).
+//     Sets acks=all and max.in.flight=1, unless specified explicitly. Requires kafka.version >= 0.11.0
+//     and retry.max >= 1.
+//   max.in.flight
+//     How many unacknowledged requests the producer is allowed to send to a broker (default 5).
+//     An alias for net.max.requests.
 //   partitioner
-//      The partitioner used to partition messages (defaults to hashing the message ID). Valid values are:
-//      hash, random, roundrobin,
+//     The partitioner used to partition messages (defaults to hashing the message ID). Valid values are:
+//     hash, random, roundrobin, manual (requires the kafka.partition attribute), attribute (hashes the value
+//     of the partitioner.attribute attribute).
+//   partitioner.attribute
+//     The message attribute to hash, required by partitioner=attribute. Messages without the
+//     attribute are partitioned by their ID.
 //   timeout
-//      The maximum duration the broker will wait the receipt of the number of acks (default 10s).
-//      This is only relevant when acks=all or a number > 1.
+//     The maximum duration the broker will wait the receipt of the number of acks (default 10s).
+//     This is only relevant when acks=all or a number > 1.
 //   flush.bytes
-//      he best-effort number of bytes needed to trigger a flush.
+//     The best-effort number of bytes needed to trigger a flush.
 //   flush.messages
-//      The best-effort number of messages needed to trigger a flush.
+//     The best-effort number of messages needed to trigger a flush.
 //   flush.frequency
-//      The best-effort frequency of flushes.
+//     The best-effort frequency of flushes.
 //   retry.max
-//      The total number of times to retry sending a message (default 3).
+//     The total number of times to retry sending a message (default 3).
 //   retry.backoff
-//      How long to wait for the cluster to settle between retries (default 100ms).
+//     How long to wait for the cluster to settle between retries (default 100ms).
+//   transactional.id
+//     Enables transactional publishing with the given ID, see TxnPublisher. Implies
+//     enable.idempotence=true, unless specified explicitly. Supported by the `kafka` scheme only.
+//   transaction.timeout
+//     The maximum duration of a transaction before it is aborted by the broker (default 1m).
 //
-// bps.NewConsumer (`kafka://` scheme) supports:
+// bps.NewSubscriber (`kafka://` scheme) supports:
 //
 //   offsets.initial
-//     Offset to start consuming from. Can be "oldest" (oldest available message)
-//     or "newest" (only new messages - produced after subscribing)
-//     or just numeric offset value.
+//     Offset to start consuming from, unless specified with bps.StartAt. Can be "oldest" (oldest
+//     available message) or "newest" (only new messages - produced after subscribing, default).
+//   fetch.min.bytes
+//     The minimum number of message bytes to fetch in a request - the broker will wait until at least
+//     this many are available (default 1).
+//   fetch.default.bytes
+//     The default number of message bytes to fetch from the broker in each request (default 1MB).
+//     This should be larger than the majority of your messages, or else the consumer will spend a lot of
+//     time negotiating sizes and not actually consuming.
+//   fetch.max.wait
+//     The maximum amount of time the broker will wait for fetch.min.bytes to become available before
+//     it returns fewer than that anyways (default 500ms).
+//   max.processing.time
+//     The maximum amount of time the consumer expects a message takes to process for the user. If
+//     handling takes longer, partition consumption is paused until handler catches up (default 100ms).
+//   isolation.level
+//     Controls how to read messages written transactionally. Valid values are: read_uncommitted,
+//     read_committed (default read_uncommitted). read_committed requires kafka.version >= 0.11.0.
+//   retry.backoff
+//     How long to wait after failing to read from a partition before trying again (default 2s).
+//   group.id
+//     Consumer group to join, see GroupSubscriber. Subscriptions consume claimed partitions only and
+//     resume from committed offsets (default none).
+//   enable.auto.commit
+//     Whether to commit offsets of handled messages periodically, requires group.id (defaults to true).
+//     Disable to commit offsets with TxnPublisher.AddMessageOffsets instead.
+//   auto.commit.interval
+//     How frequently to commit offsets of handled messages, requires group.id (default 1s).
+//
+// Publishers honour reserved message attributes, see AttrPartition, AttrTimestamp and AttrTombstone.
+// Subscriptions handle *SubMessage messages, which distinguish tombstones from empty messages.
+//
+// Consuming zstd-compressed messages requires kafka.version >= 2.1.0 (to enable zstd-aware fetch requests).
+//
+// Invalid or unknown query parameters are reported as errors.
 //
 package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bsm/bps/internal/concurrent"
 
@@ -79,24 +154,67 @@ import (
 	"github.com/bsm/bps"
 )
 
+// Reserved bps.PubMessage attributes, which are not published as message headers.
+const (
+	// AttrPartition pins the message to a partition, it is required by partitioner=manual and rejected
+	// by other partitioners.
+	AttrPartition = "kafka.partition"
+	// AttrTimestamp sets the message (event) time in RFC3339 format, requires kafka.version >= 0.10.0.
+	AttrTimestamp = "kafka.timestamp"
+	// AttrTombstone publishes a tombstone (a null value), when set to "true", see Tombstone.
+	AttrTombstone = "kafka.tombstone"
+)
+
+// Tombstone returns a tombstone message, which deletes all messages with the same ID (key)
+// from log-compacted topics.
+func Tombstone(id string) *bps.PubMessage {
+	return &bps.PubMessage{ID: id, Attributes: map[string]string{AttrTombstone: "true"}}
+}
+
 func init() {
 	bps.RegisterPublisher("kafka", func(ctx context.Context, u *url.URL) (bps.Publisher, error) {
-		config := parseProducerQuery(u.Query())
+		config, err := parseProducerQuery(u.Query())
+		if err != nil {
+			return nil, err
+		}
 		config.Producer.Return.Errors = false
+		if config.Producer.Transaction.ID != "" {
+			return NewTxnPublisher(parseAddrs(u), config)
+		}
 		return NewPublisher(parseAddrs(u), config)
 	})
 
 	bps.RegisterPublisher("kafka+sync", func(ctx context.Context, u *url.URL) (bps.Publisher, error) {
-		config := parseProducerQuery(u.Query())
+		config, err := parseProducerQuery(u.Query())
+		if err != nil {
+			return nil, err
+		}
+		if config.Producer.Transaction.ID != "" {
+			return nil, fmt.Errorf("transactional.id is not supported by %s scheme", u.Scheme)
+		}
 		config.Producer.Return.Successes = true
 		return NewSyncPublisher(parseAddrs(u), config)
 	})
 
 	bps.RegisterSubscriber("kafka", func(ctx context.Context, u *url.URL) (bps.Subscriber, error) {
-		config := parseSubscriberQuery(u.Query())
+		config, groupID, err := parseSubscriberQuery(u.Query())
+		if err != nil {
+			return nil, err
+		}
 		config.Consumer.Return.Errors = false
+		if groupID != "" {
+			return NewGroupSubscriber(parseAddrs(u), groupID, config)
+		}
 		return NewSubscriber(parseAddrs(u), config)
 	})
+
+	bps.RegisterAdmin("kafka", func(ctx context.Context, u *url.URL) (bps.Admin, error) {
+		config, err := parseAdminQuery(u.Query())
+		if err != nil {
+			return nil, err
+		}
+		return NewAdmin(parseAddrs(u), config)
+	})
 }
 
 // --------------------------------------------------------------------
@@ -104,6 +222,7 @@ func init() {
 // Publisher wraps a kafka producer and implements the bps.Publisher interface.
 type Publisher struct {
 	producer sarama.AsyncProducer
+	manual   bool // partitioner=manual
 }
 
 // NewPublisher inits a new async publisher.
@@ -112,12 +231,12 @@ func NewPublisher(addrs []string, config *sarama.Config) (*Publisher, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Publisher{producer: producer}, nil
+	return &Publisher{producer: producer, manual: manualPartitioner(config)}, nil
 }
 
 // Topic implements the bps.Publisher interface.
 func (p *Publisher) Topic(name string) bps.PubTopic {
-	return &topicAsync{name: name, producer: p.producer}
+	return &topicAsync{name: name, producer: p.producer, manual: p.manual}
 }
 
 // Close implements the bps.Publisher interface.
@@ -133,11 +252,16 @@ func (p *Publisher) Producer() sarama.AsyncProducer {
 type topicAsync struct {
 	name     string
 	producer sarama.AsyncProducer
+	manual   bool
 }
 
 // Publish implements the bps.Topic interface.
 func (t *topicAsync) Publish(_ context.Context, msg *bps.PubMessage) error {
-	t.producer.Input() <- convertMessage(t.name, msg)
+	pm, err := convertMessage(t.name, msg, t.manual)
+	if err != nil {
+		return err
+	}
+	t.producer.Input() <- pm
 	return nil
 }
 
@@ -146,6 +270,7 @@ func (t *topicAsync) Publish(_ context.Context, msg *bps.PubMessage) error {
 // SyncPublisher wraps a synchronous kafka producer and implements the bps.Publisher interface.
 type SyncPublisher struct {
 	producer sarama.SyncProducer
+	manual   bool // partitioner=manual
 }
 
 // NewSyncPublisher inits a new async publisher.
@@ -154,12 +279,12 @@ func NewSyncPublisher(addrs []string, config *sarama.Config) (*SyncPublisher, er
 	if err != nil {
 		return nil, err
 	}
-	return &SyncPublisher{producer: producer}, nil
+	return &SyncPublisher{producer: producer, manual: manualPartitioner(config)}, nil
 }
 
 // Topic implements the bps.Publisher interface.
 func (p *SyncPublisher) Topic(name string) bps.PubTopic {
-	return &topicSync{name: name, producer: p.producer}
+	return &topicSync{name: name, producer: p.producer, manual: p.manual}
 }
 
 // Close implements the bps.Publisher interface.
@@ -175,54 +300,226 @@ func (p *SyncPublisher) Producer() sarama.SyncProducer {
 type topicSync struct {
 	name     string
 	producer sarama.SyncProducer
+	manual   bool
 }
 
 // Publish implements the bps.Topic interface.
 func (t *topicSync) Publish(_ context.Context, msg *bps.PubMessage) error {
-	_, _, err := t.producer.SendMessage(convertMessage(t.name, msg))
+	pm, err := convertMessage(t.name, msg, t.manual)
+	if err != nil {
+		return err
+	}
+	_, _, err = t.producer.SendMessage(pm)
 	return err
 }
 
 // --------------------------------------------------------------------
 
+// errNoTxn is returned when publishing outside of a transaction.
+var errNoTxn = errors.New("kafka: no transaction in progress")
+
+// TxnPublisher wraps a transactional kafka producer and implements the bps.Publisher interface.
+// Messages can only be published within a transaction:
+//
+//   if err := pub.Begin(); err != nil {
+//     return err
+//   }
+//   if err := pub.Topic("topic").Publish(ctx, msg); err != nil {
+//     _ = pub.Abort()
+//     return err
+//   }
+//   return pub.Commit()
+//
+// Use AddMessageOffsets to commit offsets of messages, consumed by a GroupSubscriber, atomically
+// with published messages, which enables exactly-once consume-transform-produce pipelines:
+//
+//   sub, _ := kafka.NewGroupSubscriber(addrs, "group", config) // with config.Consumer.Offsets.AutoCommit.Enable = false
+//   sub.Topic("input").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+//     _ = pub.Begin()
+//     _ = pub.Topic("output").Publish(ctx, transform(msg))
+//     _ = pub.AddMessageOffsets(sub.GroupID(), msg.(*kafka.SubMessage))
+//     _ = pub.Commit()
+//   }))
+//
+// Downstream subscribers should use isolation.level=read_committed to skip aborted messages.
+// Aborted offsets are not committed, re-subscribe to resume from the last committed offsets.
+type TxnPublisher struct {
+	producer sarama.AsyncProducer
+	manual   bool // partitioner=manual
+}
+
+// NewTxnPublisher inits a new transactional publisher.
+// It requires config.Producer.Transaction.ID to be set.
+func NewTxnPublisher(addrs []string, config *sarama.Config) (*TxnPublisher, error) {
+	if config == nil || config.Producer.Transaction.ID == "" {
+		return nil, errors.New("kafka: transactional publisher requires Producer.Transaction.ID")
+	}
+
+	producer, err := sarama.NewAsyncProducer(addrs, config)
+	if err != nil {
+		return nil, err
+	}
+	return &TxnPublisher{producer: producer, manual: manualPartitioner(config)}, nil
+}
+
+// Topic implements the bps.Publisher interface.
+func (p *TxnPublisher) Topic(name string) bps.PubTopic {
+	return &topicTxn{name: name, producer: p.producer, manual: p.manual}
+}
+
+// Begin starts a new transaction.
+func (p *TxnPublisher) Begin() error {
+	return p.producer.BeginTxn()
+}
+
+// Commit flushes all messages published within the current transaction and commits it.
+// Failed messages are reported as an error, in which case the transaction must be aborted.
+func (p *TxnPublisher) Commit() error {
+	return p.producer.CommitTxn()
+}
+
+// Abort aborts the current transaction.
+func (p *TxnPublisher) Abort() error {
+	return p.producer.AbortTxn()
+}
+
+// AddOffsets adds consumer group offsets to the current transaction.
+// The offsets are committed on behalf of groupID only if the transaction is committed.
+// The offsets map is keyed by topic and partition, values point to the next message to consume
+// (the offset of the last consumed message + 1). Offsets must be consumed as a member of groupID,
+// see AddMessageOffsets.
+func (p *TxnPublisher) AddOffsets(groupID string, offsets map[string]map[int32]int64) error {
+	native := make(map[string][]*sarama.PartitionOffsetMetadata, len(offsets))
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			native[topic] = append(native[topic], &sarama.PartitionOffsetMetadata{
+				Partition: partition,
+				Offset:    offset,
+			})
+		}
+	}
+	return p.producer.AddOffsetsToTxn(native, groupID)
+}
+
+// AddMessageOffsets adds offsets of consumed messages to the current transaction, see AddOffsets.
+// Messages must be consumed by a GroupSubscriber of groupID. The offset after the last message of each
+// partition is committed on behalf of groupID only if the transaction is committed.
+func (p *TxnPublisher) AddMessageOffsets(groupID string, msgs ...*SubMessage) error {
+	offsets := make(map[string]map[int32]int64)
+	for _, msg := range msgs {
+		partitions, ok := offsets[msg.Topic()]
+		if !ok {
+			partitions = make(map[int32]int64)
+			offsets[msg.Topic()] = partitions
+		}
+		if next := msg.Offset() + 1; next > partitions[msg.Partition()] {
+			partitions[msg.Partition()] = next
+		}
+	}
+	return p.AddOffsets(groupID, offsets)
+}
+
+// Close implements the bps.Publisher interface.
+func (p *TxnPublisher) Close() error {
+	return p.producer.Close()
+}
+
+// Producer exposes the native producer. Use at your own risk!
+func (p *TxnPublisher) Producer() sarama.AsyncProducer {
+	return p.producer
+}
+
+type topicTxn struct {
+	name     string
+	producer sarama.AsyncProducer
+	manual   bool
+}
+
+// Publish implements the bps.Topic interface.
+func (t *topicTxn) Publish(_ context.Context, msg *bps.PubMessage) error {
+	if t.producer.TxnStatus()&sarama.ProducerTxnFlagInTransaction == 0 {
+		return errNoTxn
+	}
+	pm, err := convertMessage(t.name, msg, t.manual)
+	if err != nil {
+		return err
+	}
+	t.producer.Input() <- pm
+	return nil
+}
+
+// --------------------------------------------------------------------
+
 // Subscriber wraps a kafka consumer and implements the bps.Subscriber interface.
 type Subscriber struct {
+	client   sarama.Client
 	consumer sarama.Consumer
+	startAt  bps.StartPosition
 }
 
 // NewSubscriber inits a new subscriber.
-// By default, it starts handling from the newest available message (published after subscribing).
+// By default, it starts handling from the newest available message (published after subscribing),
+// unless config.Consumer.Offsets.Initial is set to sarama.OffsetOldest.
+//
+// Subscriptions check for new partitions every config.Metadata.RefreshFrequency and consume them
+// from the oldest available message, emitting bps.EventPartitionsChanged events.
+//
+// Subscriptions support bps.WithHandlerFactory to handle partitions in parallel.
+// Subscriptions are returned as *Subscription, which reports consumer lag.
 func NewSubscriber(addrs []string, config *sarama.Config) (*Subscriber, error) {
-	consumer, err := sarama.NewConsumer(addrs, config)
+	if config == nil {
+		config = sarama.NewConfig()
+	}
+
+	client, err := sarama.NewClient(addrs, config)
 	if err != nil {
 		return nil, err
 	}
-	return &Subscriber{consumer: consumer}, nil
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	startAt := bps.PositionNewest
+	if config.Consumer.Offsets.Initial == sarama.OffsetOldest {
+		startAt = bps.PositionOldest
+	}
+	return &Subscriber{client: client, consumer: consumer, startAt: startAt}, nil
 }
 
 // Topic returns named topic handle.
 func (s *Subscriber) Topic(name string) bps.SubTopic {
 	return &subTopic{
+		client:   s.client,
 		consumer: s.consumer,
 		name:     name,
+		startAt:  s.startAt,
 	}
 }
 
 // Close implements the bps.Subscriber interface.
 func (s *Subscriber) Close() error {
-	return s.consumer.Close()
+	err := s.consumer.Close()
+	if err2 := s.client.Close(); err2 != nil && err == nil {
+		err = err2
+	}
+	return err
 }
 
 // ----------------------------------------------------------------------------
 
 type subTopic struct {
+	client   sarama.Client
 	consumer sarama.Consumer
 	name     string
+	startAt  bps.StartPosition
 }
 
 func (t *subTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps.Subscription, error) {
 	opts := (&bps.SubOptions{
-		StartAt: bps.PositionNewest,
+		StartAt: t.startAt,
 	}).Apply(options)
 
 	var initialOffset int64
@@ -241,48 +538,484 @@ func (t *subTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps
 		return nil, fmt.Errorf("get %s partitions: %w", t.name, err)
 	}
 
-	// synchronize handler access:
+	// synchronize handler access, unless partitions are handled individually:
 	handler = bps.SafeHandler(handler)
+	handlerFor := func(int32) bps.Handler { return handler }
+	if opts.HandlerFactory != nil {
+		handlerFor = func(partition int32) bps.Handler { return opts.HandlerFactory(int(partition)) }
+	}
 
 	// init closeable subscription/thread group
-	sub := concurrent.NewGroup(context.Background())
+	sub := &Subscription{
+		group:      concurrent.NewGroup(context.Background()),
+		partitions: make(map[int32]*partitionState, len(partitions)),
+	}
 
 	// spawn partition-consuming threads:
 	for _, partition := range partitions {
-		if err := t.partition(sub, partition, initialOffset, handler, opts); err != nil {
+		if err := t.partition(sub, partition, initialOffset, handlerFor(partition), opts); err != nil {
 			_ = sub.Close()
 			return nil, fmt.Errorf("consume %s/%d partition: %w", t.name, partition, err)
 		}
 	}
 
+	// watch for new partitions:
+	if interval := t.client.Config().Metadata.RefreshFrequency; interval > 0 {
+		sub.group.Go(func() {
+			t.discover(sub, interval, handlerFor, opts)
+		})
+	}
+
 	return sub, nil
 }
 
-func (t *subTopic) partition(sub *concurrent.Group, partition int32, initialOffset int64, handler bps.Handler, opts *bps.SubOptions) error {
-	pc, err := t.consumer.ConsumePartition(t.name, partition, initialOffset)
+// discover periodically checks for new partitions and starts consuming them.
+// Partitions that were added after subscribing are consumed from the oldest message.
+func (t *subTopic) discover(sub *Subscription, interval time.Duration, handlerFor func(int32) bps.Handler, opts *bps.SubOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sub.group.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := t.client.RefreshMetadata(t.name); err != nil {
+			opts.ErrorHandler(fmt.Errorf("refresh %s metadata: %w", t.name, err))
+			continue
+		}
+
+		partitions, err := t.client.Partitions(t.name)
+		if err != nil {
+			opts.ErrorHandler(fmt.Errorf("get %s partitions: %w", t.name, err))
+			continue
+		}
+
+		for _, partition := range partitions {
+			if sub.consumes(partition) {
+				continue
+			}
+
+			if err := t.partition(sub, partition, sarama.OffsetOldest, handlerFor(partition), opts); err != nil {
+				opts.ErrorHandler(fmt.Errorf("consume %s/%d partition: %w", t.name, partition, err))
+				continue
+			}
+			opts.EventHandler(bps.SubEvent{
+				Type:   bps.EventPartitionsChanged,
+				Detail: fmt.Sprintf("consuming new partition %s/%d", t.name, partition),
+			})
+		}
+	}
+}
+
+func (t *subTopic) partition(sub *Subscription, partition int32, initialOffset int64, handler bps.Handler, opts *bps.SubOptions) error {
+	// resolve initial offset to report lag before the first message is consumed
+	offset, err := t.client.GetOffset(t.name, partition, initialOffset)
+	if err != nil {
+		return err
+	}
+
+	pc, err := t.consumer.ConsumePartition(t.name, partition, offset)
 	if err != nil {
 		return err
 	}
 
+	state := &partitionState{hwm: pc, offset: offset}
+	sub.add(partition, state)
+
 	// close partition consumer when group is finished
-	sub.Go(func() {
+	sub.group.Go(func() {
 		defer pc.Close()
-		<-sub.Done()
+		<-sub.group.Done()
 	})
 
 	// subscribe to errors
-	sub.Go(func() {
+	sub.group.Go(func() {
 		for err := range pc.Errors() {
 			opts.ErrorHandler(fmt.Errorf("consume %s/%d partition: %w", t.name, partition, err))
 		}
 	})
 
 	// subscribe to messages
-	sub.Go(func() {
+	sub.group.Go(func() {
 		for msg := range pc.Messages() {
-			handler.Handle(bps.RawSubMessage(msg.Value))
+			handler.Handle(&SubMessage{msg: msg})
+			atomic.StoreInt64(&state.offset, msg.Offset+1)
+		}
+	})
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+
+// GroupSubscriber wraps kafka consumer groups and implements the bps.Subscriber interface.
+type GroupSubscriber struct {
+	addrs   []string
+	groupID string
+	config  *sarama.Config
+	startAt bps.StartPosition
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewGroupSubscriber inits a new subscriber, which consumes topics as a member of the groupID consumer group.
+// Each subscription joins the group and consumes the claimed partitions only, starting from the committed
+// group offsets. Partitions without committed offsets are consumed from the newest available message
+// by default, unless config.Consumer.Offsets.Initial is set to sarama.OffsetOldest.
+//
+// Offsets of handled messages are committed every config.Consumer.Offsets.AutoCommit.Interval,
+// so messages are delivered at least once. Disable config.Consumer.Offsets.AutoCommit.Enable
+// to commit offsets with TxnPublisher.AddMessageOffsets instead.
+//
+// Subscriptions emit bps.EventPartitionsChanged events on rebalances and support bps.WithHandlerFactory
+// to handle partitions in parallel. Subscriptions are returned as *Subscription, which reports consumer lag
+// of the claimed partitions.
+func NewGroupSubscriber(addrs []string, groupID string, config *sarama.Config) (*GroupSubscriber, error) {
+	if groupID == "" {
+		return nil, errors.New("kafka: group subscriber requires a group ID")
+	}
+	if config == nil {
+		config = sarama.NewConfig()
+	}
+	if !config.Version.IsAtLeast(sarama.V0_10_2_0) {
+		return nil, errors.New("kafka: consumer groups require Version >= V0_10_2_0")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	startAt := bps.PositionNewest
+	if config.Consumer.Offsets.Initial == sarama.OffsetOldest {
+		startAt = bps.PositionOldest
+	}
+	return &GroupSubscriber{
+		addrs:   addrs,
+		groupID: groupID,
+		config:  config,
+		startAt: startAt,
+		subs:    make(map[*Subscription]struct{}),
+	}, nil
+}
+
+// GroupID returns the consumer group ID.
+func (s *GroupSubscriber) GroupID() string {
+	return s.groupID
+}
+
+// Topic returns named topic handle.
+func (s *GroupSubscriber) Topic(name string) bps.SubTopic {
+	return &groupTopic{subscriber: s, name: name}
+}
+
+// Close implements the bps.Subscriber interface, it closes all subscriptions.
+func (s *GroupSubscriber) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subs {
+		_ = sub.Close()
+		delete(s.subs, sub)
+	}
+	return nil
+}
+
+func (s *GroupSubscriber) add(sub *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("kafka: subscriber is closed")
+	}
+	s.subs[sub] = struct{}{}
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+
+type groupTopic struct {
+	subscriber *GroupSubscriber
+	name       string
+}
+
+func (t *groupTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps.Subscription, error) {
+	s := t.subscriber
+	opts := (&bps.SubOptions{
+		StartAt: s.startAt,
+	}).Apply(options)
+
+	// copy config, start positions only apply to this subscription:
+	config := *s.config
+	switch opts.StartAt {
+	case bps.PositionNewest:
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	case bps.PositionOldest:
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	default:
+		return nil, fmt.Errorf("start position %s is not supported by this implementation", opts.StartAt)
+	}
+
+	// consumer groups can not share clients:
+	client, err := sarama.NewClient(s.addrs, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := sarama.NewConsumerGroupFromClient(s.groupID, client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	// synchronize handler access, unless partitions are handled individually:
+	handler = bps.SafeHandler(handler)
+	handlerFor := func(int32) bps.Handler { return handler }
+	if opts.HandlerFactory != nil {
+		handlerFor = func(partition int32) bps.Handler { return opts.HandlerFactory(int(partition)) }
+	}
+
+	sub := &Subscription{
+		group:      concurrent.NewGroup(context.Background()),
+		partitions: make(map[int32]*partitionState),
+	}
+	if err := s.add(sub); err != nil {
+		_ = group.Close()
+		_ = client.Close()
+		return nil, err
+	}
+
+	gh := &groupHandler{
+		sub:        sub,
+		client:     client,
+		topic:      t.name,
+		handlerFor: handlerFor,
+		opts:       opts,
+		commit:     config.Consumer.Offsets.AutoCommit.Enable,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub.group.Go(func() {
+		<-sub.group.Done()
+		cancel()
+	})
+
+	if config.Consumer.Return.Errors {
+		sub.group.Go(func() {
+			for err := range group.Errors() {
+				opts.ErrorHandler(fmt.Errorf("consume %s: %w", t.name, err))
+			}
+		})
+	}
+
+	// consume until closed, join the group again after rebalances:
+	sub.group.Go(func() {
+		defer client.Close()
+		defer group.Close()
+
+		for {
+			if err := group.Consume(ctx, []string{t.name}, gh); err != nil {
+				opts.ErrorHandler(fmt.Errorf("consume %s: %w", t.name, err))
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(config.Consumer.Retry.Backoff):
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
 		}
 	})
 
+	return sub, nil
+}
+
+// groupHandler handles claims of a consumer group session.
+type groupHandler struct {
+	sub        *Subscription
+	client     sarama.Client
+	topic      string
+	handlerFor func(int32) bps.Handler
+	opts       *bps.SubOptions
+	commit     bool // mark handled messages to commit their offsets
+}
+
+func (h *groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	partitions := append([]int32(nil), session.Claims()[h.topic]...)
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	h.opts.EventHandler(bps.SubEvent{
+		Type:   bps.EventPartitionsChanged,
+		Detail: fmt.Sprintf("claimed %s partitions %v", h.topic, partitions),
+	})
+	return nil
+}
+
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// claims start at the committed group offset, resolve the initial offset otherwise:
+	offset := claim.InitialOffset()
+	if offset < 0 {
+		resolved, err := h.client.GetOffset(claim.Topic(), claim.Partition(), offset)
+		if err != nil {
+			// report, but keep consuming, the offset is updated once a message is handled:
+			h.opts.ErrorHandler(fmt.Errorf("get %s/%d offset: %w", claim.Topic(), claim.Partition(), err))
+			resolved = 0
+		}
+		offset = resolved
+	}
+
+	// report claimed partitions only:
+	state := &partitionState{hwm: claim, offset: offset}
+	h.sub.add(claim.Partition(), state)
+	defer h.sub.remove(claim.Partition())
+
+	handler := h.handlerFor(claim.Partition())
+	for msg := range claim.Messages() {
+		handler.Handle(&SubMessage{msg: msg})
+		atomic.StoreInt64(&state.offset, msg.Offset+1)
+		if h.commit {
+			session.MarkMessage(msg, "")
+		}
+	}
 	return nil
 }
+
+// ----------------------------------------------------------------------------
+
+// SubMessage is a message, handled by subscriptions. It implements the bps.SubMessage interface.
+type SubMessage struct {
+	msg *sarama.ConsumerMessage
+}
+
+// Data implements the bps.SubMessage interface.
+func (m *SubMessage) Data() []byte {
+	return m.msg.Value
+}
+
+// ID returns the message ID (key).
+func (m *SubMessage) ID() string {
+	return string(m.msg.Key)
+}
+
+// Tombstone returns true if the message is a tombstone (has a null value).
+func (m *SubMessage) Tombstone() bool {
+	return m.msg.Value == nil
+}
+
+// Topic returns the topic, the message was consumed from.
+func (m *SubMessage) Topic() string {
+	return m.msg.Topic
+}
+
+// Partition returns the partition, the message was consumed from.
+func (m *SubMessage) Partition() int32 {
+	return m.msg.Partition
+}
+
+// Offset returns the message offset within its partition.
+func (m *SubMessage) Offset() int64 {
+	return m.msg.Offset
+}
+
+// Native exposes the native message. Use at your own risk!
+func (m *SubMessage) Native() *sarama.ConsumerMessage {
+	return m.msg
+}
+
+// ----------------------------------------------------------------------------
+
+// Subscription is a kafka topic subscription, returned by subscriber topic Subscribe calls.
+// It implements the bps.Subscription interface.
+type Subscription struct {
+	group *concurrent.Group
+
+	mu         sync.Mutex
+	partitions map[int32]*partitionState
+}
+
+// PartitionStats contains consumer position information of a single partition.
+type PartitionStats struct {
+	// Partition is the partition number.
+	Partition int32
+	// Offset is the offset of the next message to handle. Consumer group subscriptions start
+	// at the committed group offset and commit handled offsets, unless auto-commit is disabled.
+	Offset int64
+	// HighWaterMark is the offset of the next message to be produced, as reported by the broker
+	// on the last fetch.
+	HighWaterMark int64
+	// Lag is the number of messages, that are not handled yet (HighWaterMark - Offset).
+	Lag int64
+}
+
+// Stats returns per-partition consumer positions, ordered by partition.
+// Consumer group subscriptions report partitions, that are currently claimed, only.
+func (s *Subscription) Stats() []PartitionStats {
+	s.mu.Lock()
+	stats := make([]PartitionStats, 0, len(s.partitions))
+	for partition, state := range s.partitions {
+		stats = append(stats, state.stats(partition))
+	}
+	s.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Partition < stats[j].Partition })
+	return stats
+}
+
+// Lag returns the total number of messages, that are not handled yet.
+func (s *Subscription) Lag() int64 {
+	var lag int64
+	for _, st := range s.Stats() {
+		lag += st.Lag
+	}
+	return lag
+}
+
+// Close implements the bps.Subscription interface.
+func (s *Subscription) Close() error {
+	return s.group.Close()
+}
+
+func (s *Subscription) add(partition int32, state *partitionState) {
+	s.mu.Lock()
+	s.partitions[partition] = state
+	s.mu.Unlock()
+}
+
+func (s *Subscription) remove(partition int32) {
+	s.mu.Lock()
+	delete(s.partitions, partition)
+	s.mu.Unlock()
+}
+
+func (s *Subscription) consumes(partition int32) bool {
+	s.mu.Lock()
+	_, ok := s.partitions[partition]
+	s.mu.Unlock()
+	return ok
+}
+
+type partitionState struct {
+	offset int64 // atomic, keep first for 64-bit alignment
+	hwm    interface{ HighWaterMarkOffset() int64 }
+}
+
+func (p *partitionState) stats(partition int32) PartitionStats {
+	st := PartitionStats{
+		Partition:     partition,
+		Offset:        atomic.LoadInt64(&p.offset),
+		HighWaterMark: p.hwm.HighWaterMarkOffset(),
+	}
+	if st.Lag = st.HighWaterMark - st.Offset; st.Lag < 0 {
+		st.Lag = 0 // no fetch responses yet
+	}
+	return st
+}
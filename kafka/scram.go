@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// scramClient implements sarama.SCRAMClient.
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	hashGen scram.HashGeneratorFcn
+}
+
+func newSCRAMClientGenerator(hashGen scram.HashGeneratorFcn) func() sarama.SCRAMClient {
+	return func() sarama.SCRAMClient {
+		return &scramClient{hashGen: hashGen}
+	}
+}
+
+var (
+	scramSHA256 = newSCRAMClientGenerator(sha256.New)
+	scramSHA512 = newSCRAMClientGenerator(sha512.New)
+)
+
+// Begin prepares the client for the SCRAM exchange.
+func (c *scramClient) Begin(userName, password, authzID string) (err error) {
+	if c.Client, err = c.hashGen.NewClient(userName, password, authzID); err != nil {
+		return err
+	}
+	c.ClientConversation = c.Client.NewConversation()
+	return nil
+}
+
+// Step steps client through the SCRAM exchange.
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+// Done returns true if the SCRAM exchange is complete.
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
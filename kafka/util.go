@@ -1,7 +1,14 @@
 package kafka
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net/url"
+	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -22,124 +29,439 @@ func parseAddrs(u *url.URL) []string {
 	return []string{addr}
 }
 
-func isTrueValue(v string) bool {
-	return !(v == "" || v[0] == '0' || v[0] == 'f' || v[0] == 'F' || v[0] == 'n' || v[0] == 'N')
+// urlConfig binds URL query parameters to a sarama.Config.
+type urlConfig struct {
+	*sarama.Config
+
+	tlsCA, tlsCert, tlsKey string
+	tlsInsecure            bool
+
+	// explicitly configured, not to be overridden by enable.idempotence/transactional.id
+	acksSet, maxInFlightSet, idempotenceSet bool
+
+	partitioner, partitionerAttr string
+
+	groupID       string
+	autoCommitSet bool
 }
 
-func parseCommonQuery(query url.Values) *sarama.Config {
+func newURLConfig() *urlConfig {
 	config := sarama.NewConfig()
 	config.ClientID = "bps-client"
+	return &urlConfig{Config: config}
+}
 
-	if v := query.Get("client.id"); v != "" {
-		config.ClientID = v
-	}
-	if v := query.Get("rack.id"); v != "" {
-		config.RackID = v
-	}
-	if v := query.Get("net.max.requests"); v != "" {
-		config.Net.MaxOpenRequests, _ = strconv.Atoi(v)
+// commonParams declares parameters, supported by both publishers and subscribers.
+func (c *urlConfig) commonParams(params *bps.QueryParams) {
+	params.String(&c.ClientID, "client.id", "A user-provided string sent with every request to the brokers for logging debugging, and auditing\n"+
+		"purposes.")
+	params.String(&c.RackID, "rack.id", "A rack identifier for this client. This can be any string value which indicates where this client\n"+
+		"is physically located. It corresponds with the broker config 'broker.rack'.")
+	params.Func("net.max.requests", "How many outstanding requests a connection is allowed to have before sending on it blocks (default 5).", c.setMaxInFlight)
+	params.Duration(&c.Net.DialTimeout, "net.dial.timeout", "How long to wait for the initial connection (default 30s).")
+	params.Duration(&c.Net.ReadTimeout, "net.read.timeout", "How long to wait for a response (default 30s).")
+	params.Duration(&c.Net.WriteTimeout, "net.write.timeout", "How long to wait for a transmit (default 30s).")
+	params.Bool(&c.Net.TLS.Enable, "net.tls.enable", "Whether or not to use TLS when connecting to the broker (defaults to false).")
+	params.String(&c.tlsCA, "tls.ca", "Path to a PEM-encoded CA bundle, used to verify brokers. Enables TLS.")
+	params.String(&c.tlsCert, "tls.cert", "Path to a PEM-encoded client certificate, requires tls.key. Enables TLS.")
+	params.String(&c.tlsKey, "tls.key", "Path to a PEM-encoded client key, requires tls.cert. Enables TLS.")
+	params.Bool(&c.tlsInsecure, "tls.insecure", "Skip broker certificate verification (defaults to false). Enables TLS.")
+	params.Enum("sasl.mechanism", "Enables SASL authentication with the given mechanism. Valid values are: PLAIN, SCRAM-SHA-256,\n"+
+		"SCRAM-SHA-512.", []string{
+		sarama.SASLTypePlaintext,
+		sarama.SASLTypeSCRAMSHA256,
+		sarama.SASLTypeSCRAMSHA512,
+	}, func(v string) {
+		c.Net.SASL.Enable = true
+		c.Net.SASL.Mechanism = sarama.SASLMechanism(v)
+		switch v {
+		case sarama.SASLTypeSCRAMSHA256:
+			c.Net.SASL.SCRAMClientGeneratorFunc = scramSHA256
+		case sarama.SASLTypeSCRAMSHA512:
+			c.Net.SASL.SCRAMClientGeneratorFunc = scramSHA512
+		}
+	})
+	params.String(&c.Net.SASL.User, "sasl.username", "SASL authentication username.")
+	params.String(&c.Net.SASL.Password, "sasl.password", "SASL authentication password.")
+	params.Func("kafka.version", "The version of the kafka server.", func(v string) (err error) {
+		c.Version, err = sarama.ParseKafkaVersion(v)
+		return
+	})
+	params.Int(&c.ChannelBufferSize, "channel.buffer.size", "The number of events to buffer in internal and external channels. This permits the producer to\n"+
+		"continue processing some messages in the background while user code is working, greatly improving\n"+
+		"throughput (default 256).")
+	params.Duration(&c.Metadata.RefreshFrequency, "metadata.refresh.frequency", "How frequently to refresh cluster metadata in the background (default 10m). Subscriptions\n"+
+		"check for new partitions at the same interval. Set to 0 to disable.")
+}
+
+// producerParams declares publisher-specific parameters.
+func (c *urlConfig) producerParams(params *bps.QueryParams) {
+	params.Func("acks", "The number of acks required before considering a request complete. When acks=0, the producer will\n"+
+		"not wait for any acknowledgment. When acks=1 (default) the leader will write the record to its local log but\n"+
+		"will respond without awaiting full acknowledgement from all followers. When acks=all the leader will\n"+
+		"wait for the full set of in-sync replicas to acknowledge the record.", func(v string) error {
+		c.acksSet = true
+		if v == "all" {
+			c.Producer.RequiredAcks = sarama.WaitForAll
+			return nil
+		}
+		num, err := strconv.ParseInt(v, 10, 16)
+		if err != nil {
+			return errors.New("not an integer or all")
+		}
+		c.Producer.RequiredAcks = sarama.RequiredAcks(num)
+		return nil
+	})
+	params.Int(&c.Producer.MaxMessageBytes, "message.max.bytes", "The maximum permitted size of a message (default 1,000,000). Should be\n"+
+		"set equal to or smaller than the broker's `message.max.bytes`.")
+	params.Enum("compression.type", "The compression type for all data generated by the producer. Valid values are: none, gzip, snappy,\n"+
+		"lz4, zstd (default none). zstd requires kafka.version >= 2.1.0.", []string{"none", "gzip", "snappy", "lz4", "zstd"}, func(v string) {
+		switch v {
+		case "none":
+			c.Producer.Compression = sarama.CompressionNone
+		case "gzip":
+			c.Producer.Compression = sarama.CompressionGZIP
+		case "snappy":
+			c.Producer.Compression = sarama.CompressionSnappy
+		case "lz4":
+			c.Producer.Compression = sarama.CompressionLZ4
+		case "zstd":
+			c.Producer.Compression = sarama.CompressionZSTD
+		}
+	})
+	params.Int(&c.Producer.CompressionLevel, "compression.level", "The codec-specific compression level, supported by gzip, lz4 and zstd compression types\n"+
+		"(defaults to the codec's default level).")
+	params.Func("enable.idempotence", "Whether to ensure that exactly one copy of each message is written (defaults to false).\n"+
+		"Sets acks=all and max.in.flight=1, unless specified explicitly. Requires kafka.version >= 0.11.0\n"+
+		"and retry.max >= 1.", func(v string) error {
+		ok, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		c.idempotenceSet = true
+		c.Producer.Idempotent = ok
+		return nil
+	})
+	params.Func("max.in.flight", "How many unacknowledged requests the producer is allowed to send to a broker (default 5).\n"+
+		"An alias for net.max.requests.", c.setMaxInFlight)
+	params.Enum("partitioner", "The partitioner used to partition messages (defaults to hashing the message ID). Valid values are:\n"+
+		"hash, random, roundrobin, manual (requires the kafka.partition attribute), attribute (hashes the value\n"+
+		"of the partitioner.attribute attribute).", []string{"hash", "random", "roundrobin", "manual", "attribute"}, func(v string) {
+		c.partitioner = v
+		switch v {
+		case "hash":
+			c.Producer.Partitioner = sarama.NewHashPartitioner
+		case "random":
+			c.Producer.Partitioner = sarama.NewRandomPartitioner
+		case "roundrobin":
+			c.Producer.Partitioner = sarama.NewRoundRobinPartitioner
+		case "manual":
+			c.Producer.Partitioner = sarama.NewManualPartitioner
+		}
+	})
+	params.String(&c.partitionerAttr, "partitioner.attribute", "The message attribute to hash, required by partitioner=attribute. Messages without the\n"+
+		"attribute are partitioned by their ID.")
+	params.Duration(&c.Producer.Timeout, "timeout", "The maximum duration the broker will wait the receipt of the number of acks (default 10s).\n"+
+		"This is only relevant when acks=all or a number > 1.")
+	params.Int(&c.Producer.Flush.Bytes, "flush.bytes", "The best-effort number of bytes needed to trigger a flush.")
+	params.Int(&c.Producer.Flush.Messages, "flush.messages", "The best-effort number of messages needed to trigger a flush.")
+	params.Duration(&c.Producer.Flush.Frequency, "flush.frequency", "The best-effort frequency of flushes.")
+	params.Int(&c.Producer.Retry.Max, "retry.max", "The total number of times to retry sending a message (default 3).")
+	params.Duration(&c.Producer.Retry.Backoff, "retry.backoff", "How long to wait for the cluster to settle between retries (default 100ms).")
+	params.String(&c.Producer.Transaction.ID, "transactional.id", "Enables transactional publishing with the given ID, see TxnPublisher. Implies\n"+
+		"enable.idempotence=true, unless specified explicitly. Supported by the `kafka` scheme only.")
+	params.Duration(&c.Producer.Transaction.Timeout, "transaction.timeout", "The maximum duration of a transaction before it is aborted by the broker (default 1m).")
+}
+
+// consumerParams declares subscriber-specific parameters.
+func (c *urlConfig) consumerParams(params *bps.QueryParams) {
+	params.Enum("offsets.initial", "Offset to start consuming from, unless specified with bps.StartAt. Can be \"oldest\" (oldest\n"+
+		"available message) or \"newest\" (only new messages - produced after subscribing, default).", []string{"oldest", "newest"}, func(v string) {
+		switch v {
+		case "oldest":
+			c.Consumer.Offsets.Initial = sarama.OffsetOldest
+		case "newest":
+			c.Consumer.Offsets.Initial = sarama.OffsetNewest
+		}
+	})
+	int32Param(params, &c.Consumer.Fetch.Min, "fetch.min.bytes", "The minimum number of message bytes to fetch in a request - the broker will wait until at least\n"+
+		"this many are available (default 1).")
+	int32Param(params, &c.Consumer.Fetch.Default, "fetch.default.bytes", "The default number of message bytes to fetch from the broker in each request (default 1MB).\n"+
+		"This should be larger than the majority of your messages, or else the consumer will spend a lot of\n"+
+		"time negotiating sizes and not actually consuming.")
+	params.Duration(&c.Consumer.MaxWaitTime, "fetch.max.wait", "The maximum amount of time the broker will wait for fetch.min.bytes to become available before\n"+
+		"it returns fewer than that anyways (default 500ms).")
+	params.Duration(&c.Consumer.MaxProcessingTime, "max.processing.time", "The maximum amount of time the consumer expects a message takes to process for the user. If\n"+
+		"handling takes longer, partition consumption is paused until handler catches up (default 100ms).")
+	params.Enum("isolation.level", "Controls how to read messages written transactionally. Valid values are: read_uncommitted,\n"+
+		"read_committed (default read_uncommitted). read_committed requires kafka.version >= 0.11.0.", []string{"read_uncommitted", "read_committed"}, func(v string) {
+		switch v {
+		case "read_uncommitted":
+			c.Consumer.IsolationLevel = sarama.ReadUncommitted
+		case "read_committed":
+			c.Consumer.IsolationLevel = sarama.ReadCommitted
+		}
+	})
+	params.Duration(&c.Consumer.Retry.Backoff, "retry.backoff", "How long to wait after failing to read from a partition before trying again (default 2s).")
+	params.String(&c.groupID, "group.id", "Consumer group to join, see GroupSubscriber. Subscriptions consume claimed partitions only and\n"+
+		"resume from committed offsets (default none).")
+	params.Func("enable.auto.commit", "Whether to commit offsets of handled messages periodically, requires group.id (defaults to true).\n"+
+		"Disable to commit offsets with TxnPublisher.AddMessageOffsets instead.", func(v string) error {
+		ok, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		c.autoCommitSet = true
+		c.Consumer.Offsets.AutoCommit.Enable = ok
+		return nil
+	})
+	params.Func("auto.commit.interval", "How frequently to commit offsets of handled messages, requires group.id (default 1s).", func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return errors.New("not a duration")
+		}
+		c.autoCommitSet = true
+		c.Consumer.Offsets.AutoCommit.Interval = d
+		return nil
+	})
+}
+
+func (c *urlConfig) setMaxInFlight(v string) error {
+	num, err := strconv.Atoi(v)
+	if err != nil {
+		return errors.New("not an integer")
 	}
-	if v := query.Get("net.dial.timeout"); v != "" {
-		config.Net.DialTimeout, _ = time.ParseDuration(v)
+	c.maxInFlightSet = true
+	c.Net.MaxOpenRequests = num
+	return nil
+}
+
+// int32Param declares an int32 parameter.
+func int32Param(params *bps.QueryParams, dst *int32, name, usage string) {
+	params.Func(name, usage, func(v string) error {
+		num, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		*dst = int32(num)
+		return nil
+	})
+}
+
+// build finalizes and validates the config.
+func (c *urlConfig) build() (*sarama.Config, error) {
+	if err := c.buildTLS(); err != nil {
+		return nil, err
 	}
-	if v := query.Get("net.read.timeout"); v != "" {
-		config.Net.ReadTimeout, _ = time.ParseDuration(v)
+
+	if c.partitioner == "attribute" {
+		if c.partitionerAttr == "" {
+			return nil, errors.New("partitioner=attribute requires partitioner.attribute")
+		}
+		c.Producer.Partitioner = NewAttributePartitioner(c.partitionerAttr)
+	} else if c.partitionerAttr != "" {
+		return nil, errors.New("partitioner.attribute requires partitioner=attribute")
 	}
-	if v := query.Get("net.write.timeout"); v != "" {
-		config.Net.WriteTimeout, _ = time.ParseDuration(v)
+
+	if c.autoCommitSet && c.groupID == "" {
+		return nil, errors.New("enable.auto.commit and auto.commit.interval require group.id")
 	}
-	if v := query.Get("net.tls.enable"); isTrueValue(v) {
-		config.Net.TLS.Enable = true
+
+	if c.Producer.Transaction.ID != "" && !c.idempotenceSet {
+		c.Producer.Idempotent = true
 	}
-	if v := query.Get("kafka.version"); v != "" {
-		if kv, err := sarama.ParseKafkaVersion(v); err == nil {
-			config.Version = kv
+	if c.Producer.Idempotent {
+		if !c.acksSet {
+			c.Producer.RequiredAcks = sarama.WaitForAll
+		}
+		if !c.maxInFlightSet {
+			c.Net.MaxOpenRequests = 1
+		}
+		if c.Producer.Retry.Max < 1 {
+			return nil, errors.New("enable.idempotence requires retry.max >= 1")
 		}
-	}
-	if v := query.Get("channel.buffer.size"); v != "" {
-		config.ChannelBufferSize, _ = strconv.Atoi(v)
 	}
 
-	return config
-}
-
-func parseProducerQuery(query url.Values) *sarama.Config {
-	config := parseCommonQuery(query)
-
-	if v := query.Get("acks"); v != "" {
-		if v == "all" {
-			config.Producer.RequiredAcks = sarama.WaitForAll
-		} else if num, err := strconv.ParseInt(v, 10, 32); err == nil {
-			config.Producer.RequiredAcks = sarama.RequiredAcks(num)
+	if c.Producer.CompressionLevel != sarama.CompressionLevelDefault {
+		switch c.Producer.Compression {
+		case sarama.CompressionGZIP, sarama.CompressionLZ4, sarama.CompressionZSTD:
+		default:
+			return nil, fmt.Errorf("compression.level is not supported by %s compression type", c.Producer.Compression)
 		}
 	}
 
-	if v := query.Get("message.max.bytes"); v != "" {
-		config.Producer.MaxMessageBytes, _ = strconv.Atoi(v)
+	if err := c.Validate(); err != nil {
+		return nil, err
 	}
+	return c.Config, nil
+}
 
-	switch query.Get("compression.type") {
-	case "gzip":
-		config.Producer.Compression = sarama.CompressionGZIP
-	case "snappy":
-		config.Producer.Compression = sarama.CompressionSnappy
-	case "lz4":
-		config.Producer.Compression = sarama.CompressionLZ4
+func (c *urlConfig) buildTLS() error {
+	if c.tlsCA == "" && c.tlsCert == "" && c.tlsKey == "" && !c.tlsInsecure {
+		return nil
 	}
 
-	switch query.Get("partitioner") {
-	case "hash":
-		config.Producer.Partitioner = sarama.NewHashPartitioner
-	case "random":
-		config.Producer.Partitioner = sarama.NewRandomPartitioner
-	case "roundrobin":
-		config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.tlsInsecure, //nolint:gosec // explicitly requested
 	}
 
-	if v := query.Get("timeout"); v != "" {
-		config.Producer.Timeout, _ = time.ParseDuration(v)
+	if c.tlsCA != "" {
+		pem, err := os.ReadFile(c.tlsCA)
+		if err != nil {
+			return fmt.Errorf("read tls.ca: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("read tls.ca: no certificates found in %s", c.tlsCA)
+		}
 	}
 
-	if v := query.Get("flush.bytes"); v != "" {
-		config.Producer.Flush.Bytes, _ = strconv.Atoi(v)
-	}
-	if v := query.Get("flush.messages"); v != "" {
-		config.Producer.Flush.Messages, _ = strconv.Atoi(v)
+	if c.tlsCert != "" || c.tlsKey != "" {
+		if c.tlsCert == "" {
+			return errors.New("no tls.cert provided")
+		}
+		if c.tlsKey == "" {
+			return errors.New("no tls.key provided")
+		}
+		cert, err := tls.LoadX509KeyPair(c.tlsCert, c.tlsKey)
+		if err != nil {
+			return fmt.Errorf("load tls.cert/tls.key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
-	if v := query.Get("flush.frequency"); v != "" {
-		config.Producer.Flush.Frequency, _ = time.ParseDuration(v)
+
+	c.Net.TLS.Enable = true
+	c.Net.TLS.Config = tlsConfig
+	return nil
+}
+
+func parseProducerQuery(query url.Values) (*sarama.Config, error) {
+	config := newURLConfig()
+
+	var params bps.QueryParams
+	config.commonParams(&params)
+	config.producerParams(&params)
+	if err := params.Parse(query); err != nil {
+		return nil, err
 	}
+	return config.build()
+}
 
-	if v := query.Get("retry.max"); v != "" {
-		config.Producer.Retry.Max, _ = strconv.Atoi(v)
+// parseSubscriberQuery returns the subscriber config and the consumer group ID, if any.
+func parseSubscriberQuery(query url.Values) (*sarama.Config, string, error) {
+	config := newURLConfig()
+
+	var params bps.QueryParams
+	config.commonParams(&params)
+	config.consumerParams(&params)
+	if err := params.Parse(query); err != nil {
+		return nil, "", err
 	}
-	if v := query.Get("retry.backoff"); v != "" {
-		config.Producer.Retry.Backoff, _ = time.ParseDuration(v)
+
+	built, err := config.build()
+	if err != nil {
+		return nil, "", err
 	}
+	return built, config.groupID, nil
+}
+
+func parseAdminQuery(query url.Values) (*sarama.Config, error) {
+	config := newURLConfig()
 
-	return config
+	var params bps.QueryParams
+	config.commonParams(&params)
+	if err := params.Parse(query); err != nil {
+		return nil, err
+	}
+	return config.build()
 }
 
-func parseSubscriberQuery(query url.Values) *sarama.Config {
-	config := parseCommonQuery(query)
-	// consumer defaults seem fine, add only when needed
-	return config
+// manualPartitioner reports whether config partitions messages manually (partitioner=manual).
+func manualPartitioner(config *sarama.Config) bool {
+	return config != nil && config.Producer.Partitioner != nil &&
+		reflect.ValueOf(config.Producer.Partitioner).Pointer() == reflect.ValueOf(sarama.NewManualPartitioner).Pointer()
 }
 
-func convertMessage(topic string, msg *bps.PubMessage) *sarama.ProducerMessage {
+// convertMessage converts a message, AttrPartition is required by manual partitioners and rejected by others,
+// as sarama ignores it silently otherwise.
+func convertMessage(topic string, msg *bps.PubMessage, manual bool) (*sarama.ProducerMessage, error) {
 	var key sarama.Encoder
 	if msg.ID != "" {
 		key = sarama.StringEncoder(msg.ID)
 	}
 
-	var headers []sarama.RecordHeader
-	if n := len(msg.Attributes); n != 0 {
-		headers = make([]sarama.RecordHeader, 0, n)
-		for key, val := range msg.Attributes {
-			headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(val)})
+	// only tombstones are published with null values:
+	value := msg.Data
+	if value == nil {
+		value = []byte{}
+	}
+
+	pm := &sarama.ProducerMessage{Topic: topic, Key: key, Value: sarama.ByteEncoder(value)}
+	for key, val := range msg.Attributes {
+		switch key {
+		case AttrTombstone:
+			ok, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s attribute %q: not a boolean", key, val)
+			}
+			if !ok {
+				continue
+			}
+			if msg.ID == "" {
+				return nil, errors.New("tombstones require a message ID")
+			}
+			if len(msg.Data) != 0 {
+				return nil, errors.New("tombstones must not have data")
+			}
+			pm.Value = nil
+		case AttrPartition:
+			if !manual {
+				return nil, fmt.Errorf("%s attribute requires partitioner=manual", key)
+			}
+			num, err := strconv.ParseInt(val, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s attribute %q: not an integer", key, val)
+			}
+			pm.Partition = int32(num)
+		case AttrTimestamp:
+			ts, err := time.Parse(time.RFC3339Nano, val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s attribute %q: not an RFC3339 timestamp", key, val)
+			}
+			pm.Timestamp = ts
+		default:
+			pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(val)})
 		}
 	}
 
-	value := sarama.ByteEncoder(msg.Data)
-	return &sarama.ProducerMessage{Topic: topic, Key: key, Value: value, Headers: headers}
+	if _, ok := msg.Attributes[AttrPartition]; manual && !ok {
+		return nil, fmt.Errorf("partitioner=manual requires the %s attribute", AttrPartition)
+	}
+	return pm, nil
+}
+
+// NewAttributePartitioner returns a sarama.PartitionerConstructor, which hashes the value
+// of the named message attribute (header). Messages without the attribute are hashed by key.
+func NewAttributePartitioner(name string) sarama.PartitionerConstructor {
+	return func(topic string) sarama.Partitioner {
+		return &attrPartitioner{name: []byte(name), hash: sarama.NewHashPartitioner(topic)}
+	}
+}
+
+type attrPartitioner struct {
+	name []byte
+	hash sarama.Partitioner
+}
+
+func (p *attrPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	for _, h := range msg.Headers {
+		if bytes.Equal(h.Key, p.name) {
+			return p.hash.Partition(&sarama.ProducerMessage{Key: sarama.ByteEncoder(h.Value)}, numPartitions)
+		}
+	}
+	return p.hash.Partition(msg, numPartitions)
+}
+
+func (p *attrPartitioner) RequiresConsistency() bool {
+	return true
 }
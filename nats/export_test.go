@@ -0,0 +1,8 @@
+package nats
+
+import "github.com/bsm/bps"
+
+// QueryParams declares URL query parameters, documented in the package doc.
+var QueryParams = []func(*bps.QueryParams){
+	newConnConfig().params,
+}
@@ -3,15 +3,21 @@ package nats_test
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bsm/bps"
 	"github.com/bsm/bps/internal/lint"
+	bpsnats "github.com/bsm/bps/nats"
+	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
 
 	. "github.com/bsm/ginkgo"
+	"github.com/bsm/ginkgo/config"
 	. "github.com/bsm/gomega"
 )
 
@@ -33,6 +39,20 @@ var _ = Describe("Publisher", func() {
 		Expect(subject).NotTo(BeNil())
 	})
 
+	It("should init from URL with connection options", func() {
+		pub, err := bps.NewPublisher(ctx, "nats://"+natsAddrs+","+natsAddrs+"?max_reconnects=-1&reconnect_wait=100ms&reconnect_buf_size=1024")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Close()).To(Succeed())
+	})
+
+	It("should fail on invalid connection options", func() {
+		_, err := bps.NewPublisher(ctx, "nats://"+natsAddrs+"?reconnect_wait=bad&max_reconnect=1")
+		Expect(err).To(MatchError(`invalid URL query: unknown parameter "max_reconnect"; invalid reconnect_wait value "bad": not a duration`))
+
+		_, err = bps.NewPublisher(ctx, "nats://"+natsAddrs+"?nkey_seed=/does/not/exist")
+		Expect(err).To(MatchError(ContainSubstring("load nkey_seed")))
+	})
+
 	Context("lint", func() {
 		var shared lint.PublisherInput
 
@@ -81,18 +101,76 @@ var _ = Describe("Subscriber", func() {
 	})
 })
 
+var _ = Describe("Subscriber (events)", func() {
+	var srv *server.Server
+	var subject bps.Subscriber
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		srv = runServer(-1)
+
+		var err error
+		subject, err = bps.NewSubscriber(ctx, fmt.Sprintf("nats://%s?reconnect_wait=10ms", srv.Addr()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+		srv.Shutdown()
+	})
+
+	It("should report connection lifecycle events", func() {
+		var events []bps.SubEventType
+		var mu sync.Mutex
+
+		sub, err := subject.Topic("bps-unittest-events").Subscribe(
+			bps.HandlerFunc(func(bps.SubMessage) {}),
+			bps.WithEventHandler(func(e bps.SubEvent) {
+				mu.Lock()
+				events = append(events, e.Type)
+				mu.Unlock()
+			}),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		port := srv.Addr().(*net.TCPAddr).Port
+		srv.Shutdown()
+		srv = runServer(port)
+
+		Eventually(func() []bps.SubEventType {
+			mu.Lock()
+			defer mu.Unlock()
+			return events
+		}, 3*time.Second).Should(Equal([]bps.SubEventType{bps.EventDisconnected, bps.EventReconnected}))
+	})
+})
+
+var _ = Describe("URL options", func() {
+	lint.QueryDoc("nats.go", bpsnats.QueryParams...)
+})
+
 // ----------------------------------------------------------------------------
 
 func TestSuite(t *testing.T) {
+	// specs, that need no running server, are run without BPS_TEST too:
 	if !strings.Contains(os.Getenv("BPS_TEST"), "nats") {
-		t.Skipf("skipping test")
-		return
+		config.GinkgoConfig.FocusStrings = []string{`URL options`}
 	}
 
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "bps/nats")
 }
 
+func runServer(port int) *server.Server {
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: port})
+	Expect(err).NotTo(HaveOccurred())
+
+	go srv.Start()
+	Expect(srv.ReadyForConnections(time.Second)).To(BeTrue())
+	return srv
+}
+
 func readMessages(topic string, count int) ([]*bps.PubMessage, error) {
 	conn, err := nats.Connect("nats://" + natsAddrs)
 	if err != nil {
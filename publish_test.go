@@ -67,4 +67,14 @@ var _ = Describe("InMemPublisher", func() {
 
 		lint.PublisherPositionOldest(&shared)
 	})
+
+	Context("admin lint", func() {
+		var shared lint.AdminInput
+
+		BeforeEach(func() {
+			shared = lint.AdminInput{Subject: subject}
+		})
+
+		lint.Admin(&shared)
+	})
 })
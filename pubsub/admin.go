@@ -0,0 +1,94 @@
+package pubsub
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	native "cloud.google.com/go/pubsub"
+	"github.com/bsm/bps"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Admin wraps a google pubsub client and implements the bps.Admin interface.
+// Partitions and replication are not supported.
+type Admin struct {
+	client *native.Client
+}
+
+// NewAdmin inits an admin.
+func NewAdmin(ctx context.Context, projectID string, opts ...option.ClientOption) (*Admin, error) {
+	client, err := native.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Admin{client: client}, nil
+}
+
+// CreateTopic implements the bps.Admin interface.
+func (a *Admin) CreateTopic(ctx context.Context, name string, config *bps.TopicConfig) error {
+	tc := new(native.TopicConfig)
+	if config != nil && config.Retention > 0 {
+		tc.RetentionDuration = config.Retention
+	}
+
+	_, err := a.client.CreateTopicWithConfig(ctx, name, tc)
+	if status.Code(err) == codes.AlreadyExists {
+		return bps.ErrTopicExists
+	}
+	return err
+}
+
+// DeleteTopic implements the bps.Admin interface.
+func (a *Admin) DeleteTopic(ctx context.Context, name string) error {
+	err := a.client.Topic(name).Delete(ctx)
+	if status.Code(err) == codes.NotFound {
+		return bps.ErrTopicNotFound
+	}
+	return err
+}
+
+// ListTopics implements the bps.Admin interface.
+func (a *Admin) ListTopics(ctx context.Context) ([]string, error) {
+	var names []string
+	for it := a.client.Topics(ctx); ; {
+		topic, err := it.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		names = append(names, topic.ID())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DescribeTopic implements the bps.Admin interface.
+func (a *Admin) DescribeTopic(ctx context.Context, name string) (*bps.TopicInfo, error) {
+	tc, err := a.client.Topic(name).Config(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, bps.ErrTopicNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	info := &bps.TopicInfo{Name: name}
+	if d, ok := tc.RetentionDuration.(time.Duration); ok {
+		info.Retention = d
+	}
+	return info, nil
+}
+
+// Close implements the bps.Admin interface.
+func (a *Admin) Close() error {
+	return a.client.Close()
+}
+
+// Client exposes the native client. Use at your own risk!
+func (a *Admin) Client() *native.Client {
+	return a.client
+}
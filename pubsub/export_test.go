@@ -0,0 +1,10 @@
+package pubsub
+
+import "github.com/bsm/bps"
+
+// QueryParams declares URL query parameters, documented in the package doc.
+var QueryParams = []func(*bps.QueryParams){
+	new(clientConfig).params,
+	newPublishConfig().params,
+	newReceiveConfig().params,
+}
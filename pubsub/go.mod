@@ -8,6 +8,7 @@ require (
 	github.com/bsm/ginkgo v1.16.5
 	github.com/bsm/gomega v1.17.0
 	google.golang.org/api v0.88.0
+	google.golang.org/grpc v1.48.0
 )
 
 require (
@@ -28,6 +29,5 @@ require (
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/genproto v0.0.0-20220720214146-176da50484ac // indirect
-	google.golang.org/grpc v1.48.0 // indirect
 	google.golang.org/protobuf v1.28.0 // indirect
 )
@@ -1,11 +1,69 @@
 // Package pubsub provides a Google PubSub abstraction.
+//
+// URL host is used as a project ID:
+//
+//   pubsub://project-id
+//
+// All of bps.NewPublisher, bps.NewSubscriber and bps.NewAdmin support:
+//
+//   project_id
+//     Google Cloud project ID, overrides the one from URL host.
+//   emulator_host
+//     Address (host:port) of a PubSub emulator or fake server to connect to, without
+//     authentication and TLS. Takes precedence over the PUBSUB_EMULATOR_HOST environment variable.
+//   credentials_file
+//     Path to a service account or refresh token JSON credentials file.
+//   endpoint
+//     Overrides the default service endpoint (host:port).
+//   quota_project
+//     Google Cloud project ID, used for quota and billing purposes.
+//
+// bps.NewPublisher additionally supports:
+//
+//   delay_threshold
+//     Publish a non-empty batch after this delay has passed (default 10ms).
+//   count_threshold
+//     Publish a batch when it has this many messages (default 100).
+//   byte_threshold
+//     Publish a batch when its size in bytes reaches this value (default 1e6).
+//   publish_timeout
+//     The maximum time that the client will attempt to publish a batch of messages (default 60s).
+//   max_outstanding_messages
+//     Maximum number of buffered messages to be published (default 1000).
+//   max_outstanding_bytes
+//     Maximum size of buffered messages to be published (default unlimited).
+//   limit_exceeded_behavior
+//     What to do when flow control limits are exceeded. Valid values are: ignore (default), block,
+//     signal_error.
+//   message_ordering
+//     Enables message ordering, using message IDs as ordering keys (default false).
+//
+// bps.NewSubscriber additionally supports:
+//
+//   max_outstanding_messages
+//     Maximum number of unprocessed messages (default 1000).
+//   max_outstanding_bytes
+//     Maximum size of unprocessed messages (default 1e9).
+//   num_goroutines
+//     Number of goroutines, pulling messages from the server (default 10).
+//   max_extension
+//     Maximum period for which the ack deadline of a message is extended (default 60m).
+//   synchronous
+//     Uses synchronous pull instead of streaming pull (default false).
+//
+// Subscriptions call handlers concurrently (up to max_outstanding_messages at a time),
+// so handlers must be safe for concurrent use, see bps.SafeHandler.
+//
+// Invalid or unknown query parameters are reported as errors.
+//
 package pubsub
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/url"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/bsm/bps/internal/concurrent"
@@ -13,24 +71,43 @@ import (
 	native "cloud.google.com/go/pubsub"
 	"github.com/bsm/bps"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 func init() {
 	bps.RegisterPublisher("pubsub", func(ctx context.Context, u *url.URL) (bps.Publisher, error) {
-		projectID := u.Host
-		query := u.Query()
-		if v := query.Get("project_id"); v != "" {
-			projectID = v
+		pubConfig := newPublishConfig()
+		config, opts, err := parseClientConfig(u, pubConfig.params)
+		if err != nil {
+			return nil, err
 		}
-		return NewPublisher(ctx, projectID, nil)
+
+		pub, err := NewPublisher(ctx, config.projectID, &pubConfig.settings, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if pubConfig.ordering {
+			pub.EnableMessageOrdering()
+		}
+		return pub, nil
 	})
 	bps.RegisterSubscriber("pubsub", func(ctx context.Context, u *url.URL) (bps.Subscriber, error) {
-		projectID := u.Host
-		query := u.Query()
-		if v := query.Get("project_id"); v != "" {
-			projectID = v
+		recvConfig := newReceiveConfig()
+		config, opts, err := parseClientConfig(u, recvConfig.params)
+		if err != nil {
+			return nil, err
 		}
-		return NewSubscriber(ctx, projectID)
+		return NewSubscriberWithSettings(ctx, config.projectID, &recvConfig.settings, opts...)
+	})
+	bps.RegisterAdmin("pubsub", func(ctx context.Context, u *url.URL) (bps.Admin, error) {
+		config, opts, err := parseClientConfig(u)
+		if err != nil {
+			return nil, err
+		}
+		return NewAdmin(ctx, config.projectID, opts...)
 	})
 }
 
@@ -40,11 +117,13 @@ func init() {
 type Publisher struct {
 	client   *native.Client
 	settings *native.PublishSettings
+	ordering bool
 	topics   map[string]*PubTopic
 	mu       sync.RWMutex
 }
 
 // NewPublisher inits a publisher.
+// Settings are optional, native.DefaultPublishSettings are used by default.
 func NewPublisher(ctx context.Context, projectID string, settings *native.PublishSettings, opts ...option.ClientOption) (*Publisher, error) {
 	client, err := native.NewClient(ctx, projectID, opts...)
 	if err != nil {
@@ -76,13 +155,42 @@ func (p *Publisher) Topic(name string) bps.PubTopic {
 		if p.settings != nil {
 			nt.PublishSettings = *p.settings
 		}
-		topic = &PubTopic{topic: nt}
+		nt.EnableMessageOrdering = p.ordering
+		topic = &PubTopic{topic: nt, pending: make(map[*native.PublishResult]string)}
 		p.topics[name] = topic
 	}
 	return topic
 }
 
+// EnableMessageOrdering enables message ordering for topics, that are not used yet.
+// Messages are ordered by ID (used as an ordering key), messages without ID are not ordered.
+// The subscriptions must have message ordering enabled too.
+func (p *Publisher) EnableMessageOrdering() {
+	p.mu.Lock()
+	p.ordering = true
+	p.mu.Unlock()
+}
+
+// Flush sends all pending messages and waits for the results.
+// It returns a *PublishError, which contains all errors, occurred since the previous Flush.
+func (p *Publisher) Flush(ctx context.Context) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var errs []error
+	for _, t := range p.topics {
+		if err := t.Flush(ctx); err != nil {
+			errs = append(errs, flattenPublishError(err)...)
+		}
+	}
+	if len(errs) != 0 {
+		return &PublishError{Errors: errs}
+	}
+	return nil
+}
+
 // Close implements the bps.Publisher interface.
+// It returns a *PublishError, which contains all errors, occurred since the last Flush.
 func (p *Publisher) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -96,14 +204,17 @@ func (p *Publisher) Close() error {
 	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Minute))
 	defer cancel()
 
-	var err error
+	var errs []error
 	for name, t := range p.topics {
-		if e := t.wait(ctx); e != nil {
-			err = e
+		if err := t.wait(ctx); err != nil {
+			errs = append(errs, flattenPublishError(err)...)
 		}
 		delete(p.topics, name)
 	}
-	return err
+	if len(errs) != 0 {
+		return &PublishError{Errors: errs}
+	}
+	return nil
 }
 
 // Client exposes the native client. Use at your own risk!
@@ -116,59 +227,167 @@ func (p *Publisher) Client() *native.Client {
 // PubTopic wraps a pubsub topic.
 type PubTopic struct {
 	topic *native.Topic
-	last  atomic.Value
+
+	mu      sync.Mutex
+	pending map[*native.PublishResult]string // results by ordering key
+	errs    []error
+	paused  map[string]struct{} // ordering keys, paused due to errors
 }
 
 // Publish implements the bps.Topic interface.
+// Errors are reported asynchronously by Flush and Close.
 func (t *PubTopic) Publish(ctx context.Context, msg *bps.PubMessage) error {
+	var orderingKey string
+	if t.topic.EnableMessageOrdering {
+		orderingKey = msg.ID
+	}
+
 	res := t.topic.Publish(ctx, &native.Message{
-		ID:         msg.ID,
-		Data:       msg.Data,
-		Attributes: msg.Attributes,
+		ID:          msg.ID,
+		Data:        msg.Data,
+		Attributes:  msg.Attributes,
+		OrderingKey: orderingKey,
 	})
-	t.last.Store(res)
+
+	t.mu.Lock()
+	t.pending[res] = orderingKey
+	t.mu.Unlock()
+
+	go func() {
+		<-res.Ready()
+		t.done(res)
+	}()
 	return nil
 }
 
+// Flush sends all pending messages and waits for the results.
+// It returns a *PublishError, which contains all errors, occurred since the previous Flush,
+// and resumes publishing for ordering keys, paused due to these errors.
+func (t *PubTopic) Flush(ctx context.Context) error {
+	t.topic.Flush()
+	return t.wait(ctx)
+}
+
 // Topic returns the native pubsub Topic. Use at your own risk!
 func (t *PubTopic) Topic() *native.Topic {
 	return t.topic
 }
 
+// done records the result of a published message.
+func (t *PubTopic) done(res *native.PublishResult) {
+	_, err := res.Get(context.Background()) // ready, returns immediately
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	orderingKey, ok := t.pending[res]
+	if !ok {
+		return
+	}
+	delete(t.pending, res)
+
+	if err != nil {
+		t.errs = append(t.errs, err)
+		if orderingKey != "" {
+			if t.paused == nil {
+				t.paused = make(map[string]struct{})
+			}
+			t.paused[orderingKey] = struct{}{}
+		}
+	}
+}
+
+// wait waits for pending results, reports and resets errors.
 func (t *PubTopic) wait(ctx context.Context) error {
-	if v := t.last.Load(); v != nil {
-		_, err := v.(*native.PublishResult).Get(ctx)
-		return err
+	t.mu.Lock()
+	pending := make([]*native.PublishResult, 0, len(t.pending))
+	for res := range t.pending {
+		pending = append(pending, res)
+	}
+	t.mu.Unlock()
+
+	for _, res := range pending {
+		select {
+		case <-res.Ready():
+			t.done(res)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	t.mu.Lock()
+	errs, paused := t.errs, t.paused
+	t.errs, t.paused = nil, nil
+	t.mu.Unlock()
+
+	for orderingKey := range paused {
+		t.topic.ResumePublish(orderingKey)
+	}
+	if len(errs) != 0 {
+		return &PublishError{Errors: errs}
 	}
 	return nil
 }
 
+// PublishError is returned when messages failed to publish.
+type PublishError struct {
+	Errors []error
+}
+
+// Error implements error interface.
+func (e *PublishError) Error() string {
+	if len(e.Errors) == 1 {
+		return "publish: " + e.Errors[0].Error()
+	}
+	return fmt.Sprintf("publish: %d messages failed, first error: %s", len(e.Errors), e.Errors[0])
+}
+
+func flattenPublishError(err error) []error {
+	if e, ok := err.(*PublishError); ok {
+		return e.Errors
+	}
+	return []error{err}
+}
+
 // --------------------------------------------------------------------
 
 // Subscriber is a Google PubSub wrapper that implements bps.Subscriber interface.
 type Subscriber struct {
-	client *native.Client
+	client   *native.Client
+	settings *native.ReceiveSettings
 }
 
-// NewSubscriber inits a subscriber.
+// NewSubscriber inits a subscriber with native.DefaultReceiveSettings.
 // It starts handling from the newest available message (published after subscribing).
 // Google PubSub may re-deliver successfully handled messages.
-func NewSubscriber(ctx context.Context, projectID string) (*Subscriber, error) {
-	client, err := native.NewClient(ctx, projectID)
+// Handlers are called concurrently, so they must be safe for concurrent use,
+// wrap them with bps.SafeHandler to handle messages one at a time.
+func NewSubscriber(ctx context.Context, projectID string, opts ...option.ClientOption) (*Subscriber, error) {
+	return NewSubscriberWithSettings(ctx, projectID, nil, opts...)
+}
+
+// NewSubscriberWithSettings inits a subscriber with custom receive settings.
+// Settings are optional, native.DefaultReceiveSettings are used by default;
+// bps.WithMaxInflight and bps.WithMaxInflightBytes override them per subscription.
+// See NewSubscriber for delivery semantics.
+func NewSubscriberWithSettings(ctx context.Context, projectID string, settings *native.ReceiveSettings, opts ...option.ClientOption) (*Subscriber, error) {
+	client, err := native.NewClient(ctx, projectID, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Subscriber{
-		client: client,
+		client:   client,
+		settings: settings,
 	}, nil
 }
 
 // Topic returns a subcriber topic handle.
 func (s *Subscriber) Topic(name string) bps.SubTopic {
 	return &subTopic{
-		client: s.client,
-		name:   name,
+		client:   s.client,
+		settings: s.settings,
+		name:     name,
 	}
 }
 
@@ -180,8 +399,9 @@ func (s *Subscriber) Close() error {
 // --------------------------------------------------------------------
 
 type subTopic struct {
-	client *native.Client
-	name   string
+	client   *native.Client
+	settings *native.ReceiveSettings
+	name     string
 }
 
 func (t *subTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps.Subscription, error) {
@@ -198,7 +418,24 @@ func (t *subTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps
 		return nil, err
 	}
 
+	if t.settings != nil {
+		gsub.ReceiveSettings = *t.settings
+	}
+	if opts.MaxInflight != 0 {
+		gsub.ReceiveSettings.MaxOutstandingMessages = opts.MaxInflight
+	}
+	if opts.MaxInflightBytes != 0 {
+		gsub.ReceiveSettings.MaxOutstandingBytes = opts.MaxInflightBytes
+	}
+
 	sub := concurrent.NewGroup(ctx)
+
+	// stop receiving when subscription is closed:
+	sub.Go(func() {
+		defer cancel()
+		<-sub.Done()
+	})
+
 	sub.Go(func() {
 		defer func() {
 			// give subscription 5s to delete:
@@ -210,25 +447,155 @@ func (t *subTopic) Subscribe(handler bps.Handler, options ...bps.SubOption) (bps
 
 		defer cancel()
 
-		// gsub.Receive calls handler concurrently from multiple goroutines, so synchronise it:
-		handler = bps.SafeHandler(handler)
-
-		// TODO: may need to suppress sub.Receive's err:
-		//       pubsub native lib is based on streaming pull, which is expected to terminate with error:
-		//       https://cloud.google.com/pubsub/docs/pull#streamingpull_has_a_100_error_rate_this_is_to_be_expected
-		//       StreamingPull streams are always terminated with a non-OK status.
-		//       Note that, unlike in regular RPCs, the status here is simply an indication that the stream has been broken, not that requests are failing.
-		//       Therefore, while the StreamingPull API may have a seemingly surprising 100% error rate, this is by design.
-
-		// Receive returns on fatal/non-retryable errors, so thread is terminated after it, no retries:
+		// Receive returns on fatal/non-retryable errors, so thread is terminated after it, no retries.
+		// It calls handler concurrently, up to MaxOutstandingMessages at a time:
 		err := gsub.Receive(ctx, func(ctx context.Context, msg *native.Message) {
 			defer msg.Nack() // only first call to Ack/Nack matters, so it's safe
 
 			handler.Handle(bps.RawSubMessage(msg.Data))
 			msg.Ack() // no error returned, msg will be re-delivered on Ack failure
 		})
-		opts.ErrorHandler(err)
+		if !isReceiveTermination(ctx, err) {
+			opts.ErrorHandler(err)
+		}
 	})
 
 	return sub, nil
 }
+
+// isReceiveTermination reports whether Receive error is expected and not a failure.
+//
+// StreamingPull streams are always terminated with a non-OK status, which indicates
+// that the stream has been broken, not that requests are failing:
+// https://cloud.google.com/pubsub/docs/pull#streamingpull_has_a_100_error_rate_this_is_to_be_expected
+// Native lib retries broken streams, so these statuses are returned only on shutdown.
+func isReceiveTermination(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return true
+	}
+	if ctx.Err() == nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
+// --------------------------------------------------------------------
+
+// clientConfig binds URL query parameters to client options.
+type clientConfig struct {
+	projectID       string
+	emulatorHost    string
+	credentialsFile string
+	endpoint        string
+	quotaProject    string
+}
+
+// params declares parameters, supported by publishers, subscribers and admins.
+func (c *clientConfig) params(params *bps.QueryParams) {
+	params.String(&c.projectID, "project_id", "Google Cloud project ID, overrides the one from URL host.")
+	params.String(&c.emulatorHost, "emulator_host", "Address (host:port) of a PubSub emulator or fake server to connect to, without\n"+
+		"authentication and TLS. Takes precedence over the PUBSUB_EMULATOR_HOST environment variable.")
+	params.String(&c.credentialsFile, "credentials_file", "Path to a service account or refresh token JSON credentials file.")
+	params.String(&c.endpoint, "endpoint", "Overrides the default service endpoint (host:port).")
+	params.String(&c.quotaProject, "quota_project", "Google Cloud project ID, used for quota and billing purposes.")
+}
+
+// options returns client options.
+func (c *clientConfig) options() ([]option.ClientOption, error) {
+	var opts []option.ClientOption
+	if c.emulatorHost != "" {
+		if c.credentialsFile != "" || c.endpoint != "" {
+			return nil, errors.New("emulator_host cannot be combined with credentials_file or endpoint")
+		}
+		opts = append(opts,
+			option.WithEndpoint(c.emulatorHost),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		)
+	}
+	if c.credentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(c.credentialsFile))
+	}
+	if c.endpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.endpoint))
+	}
+	if c.quotaProject != "" {
+		opts = append(opts, option.WithQuotaProject(c.quotaProject))
+	}
+	return opts, nil
+}
+
+// parseClientConfig parses client config from URL, along with optional additional parameters.
+func parseClientConfig(u *url.URL, extra ...func(*bps.QueryParams)) (*clientConfig, []option.ClientOption, error) {
+	config := &clientConfig{projectID: u.Host}
+
+	var params bps.QueryParams
+	config.params(&params)
+	for _, fn := range extra {
+		fn(&params)
+	}
+	if err := params.Parse(u.Query()); err != nil {
+		return nil, nil, err
+	}
+
+	opts, err := config.options()
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, opts, nil
+}
+
+// publishConfig binds URL query parameters to publish settings.
+type publishConfig struct {
+	settings native.PublishSettings
+	ordering bool
+}
+
+func newPublishConfig() *publishConfig {
+	return &publishConfig{settings: native.DefaultPublishSettings}
+}
+
+// params declares publisher-specific parameters.
+func (c *publishConfig) params(params *bps.QueryParams) {
+	params.Duration(&c.settings.DelayThreshold, "delay_threshold", "Publish a non-empty batch after this delay has passed (default 10ms).")
+	params.Int(&c.settings.CountThreshold, "count_threshold", "Publish a batch when it has this many messages (default 100).")
+	params.Int(&c.settings.ByteThreshold, "byte_threshold", "Publish a batch when its size in bytes reaches this value (default 1e6).")
+	params.Duration(&c.settings.Timeout, "publish_timeout", "The maximum time that the client will attempt to publish a batch of messages (default 60s).")
+	params.Int(&c.settings.FlowControlSettings.MaxOutstandingMessages, "max_outstanding_messages", "Maximum number of buffered messages to be published (default 1000).")
+	params.Int(&c.settings.FlowControlSettings.MaxOutstandingBytes, "max_outstanding_bytes", "Maximum size of buffered messages to be published (default unlimited).")
+	params.Enum("limit_exceeded_behavior", "What to do when flow control limits are exceeded. Valid values are: ignore (default), block,\n"+
+		"signal_error.", []string{"ignore", "block", "signal_error"}, func(v string) {
+		switch v {
+		case "ignore":
+			c.settings.FlowControlSettings.LimitExceededBehavior = native.FlowControlIgnore
+		case "block":
+			c.settings.FlowControlSettings.LimitExceededBehavior = native.FlowControlBlock
+		case "signal_error":
+			c.settings.FlowControlSettings.LimitExceededBehavior = native.FlowControlSignalError
+		}
+	})
+	params.Bool(&c.ordering, "message_ordering", "Enables message ordering, using message IDs as ordering keys (default false).")
+}
+
+// receiveConfig binds URL query parameters to receive settings.
+type receiveConfig struct {
+	settings native.ReceiveSettings
+}
+
+func newReceiveConfig() *receiveConfig {
+	return &receiveConfig{settings: native.DefaultReceiveSettings}
+}
+
+// params declares subscriber-specific parameters.
+func (c *receiveConfig) params(params *bps.QueryParams) {
+	params.Int(&c.settings.MaxOutstandingMessages, "max_outstanding_messages", "Maximum number of unprocessed messages (default 1000).")
+	params.Int(&c.settings.MaxOutstandingBytes, "max_outstanding_bytes", "Maximum size of unprocessed messages (default 1e9).")
+	params.Int(&c.settings.NumGoroutines, "num_goroutines", "Number of goroutines, pulling messages from the server (default 10).")
+	params.Duration(&c.settings.MaxExtension, "max_extension", "Maximum period for which the ack deadline of a message is extended (default 60m).")
+	params.Bool(&c.settings.Synchronous, "synchronous", "Uses synchronous pull instead of streaming pull (default false).")
+}
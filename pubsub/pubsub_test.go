@@ -6,16 +6,19 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	native "cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
 	"github.com/bsm/bps"
 	"github.com/bsm/bps/internal/lint"
 	"github.com/bsm/bps/pubsub"
 	"google.golang.org/api/iterator"
 
 	. "github.com/bsm/ginkgo"
+	"github.com/bsm/ginkgo/config"
 	. "github.com/bsm/gomega"
 )
 
@@ -99,14 +102,228 @@ var _ = Describe("Subscriber", func() {
 	})
 })
 
+var _ = Describe("Admin", func() {
+	var subject *pubsub.Admin
+	var _ bps.Admin = subject
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		admin, err := bps.NewAdmin(ctx, "pubsub://"+projectID)
+		Expect(err).NotTo(HaveOccurred())
+
+		subject = admin.(*pubsub.Admin)
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+	})
+
+	Context("lint", func() {
+		var shared lint.AdminInput
+
+		BeforeEach(func() {
+			shared = lint.AdminInput{Subject: subject}
+		})
+
+		lint.Admin(&shared)
+	})
+})
+
+var _ = Describe("URL options", func() {
+	var srv *pstest.Server
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		srv = pstest.NewServer()
+	})
+
+	AfterEach(func() {
+		Expect(srv.Close()).To(Succeed())
+	})
+
+	It("should connect to emulators", func() {
+		url := "pubsub://" + projectID + "?emulator_host=" + srv.Addr + "&quota_project=" + projectID
+
+		admin, err := bps.NewAdmin(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		defer admin.Close()
+		Expect(admin.CreateTopic(ctx, "topic", nil)).To(Succeed())
+
+		sub, err := bps.NewSubscriber(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			select {
+			case received <- string(msg.Data()):
+			default:
+			}
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		pub, err := bps.NewPublisher(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+		Expect(pub.Close()).To(Succeed())
+
+		Eventually(received, 5*time.Second).Should(Receive(Equal("v1")))
+	})
+
+	It("should configure receive settings", func() {
+		url := "pubsub://" + projectID + "?emulator_host=" + srv.Addr
+
+		admin, err := bps.NewAdmin(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		defer admin.Close()
+		Expect(admin.CreateTopic(ctx, "topic", nil)).To(Succeed())
+
+		sub, err := bps.NewSubscriber(ctx, url+"&synchronous=true&num_goroutines=1&max_outstanding_messages=1&max_extension=1m")
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		var errs []error
+		var mu sync.Mutex
+		received := make(chan string, 10)
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			select {
+			case received <- string(msg.Data()):
+			default:
+			}
+		}), bps.WithMaxInflightBytes(1e6), bps.WithErrorHandler(func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}))
+		Expect(err).NotTo(HaveOccurred())
+
+		pub, err := bps.NewPublisher(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+		Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).To(Succeed())
+		Expect(pub.Close()).To(Succeed())
+
+		// delivery order is not guaranteed:
+		var v1, v2 string
+		Eventually(received, 5*time.Second).Should(Receive(&v1))
+		Eventually(received, 5*time.Second).Should(Receive(&v2))
+		Expect([]string{v1, v2}).To(ConsistOf("v1", "v2"))
+		Expect(subscription.Close()).To(Succeed())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("should handle messages in parallel", func() {
+		url := "pubsub://" + projectID + "?emulator_host=" + srv.Addr
+
+		admin, err := bps.NewAdmin(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		defer admin.Close()
+		Expect(admin.CreateTopic(ctx, "topic", nil)).To(Succeed())
+
+		sub, err := bps.NewSubscriber(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		// handlers block until all messages are handled at the same time:
+		var active int32
+		var once sync.Once
+		parallel := make(chan struct{})
+		subscription, err := sub.Topic("topic").Subscribe(bps.HandlerFunc(func(msg bps.SubMessage) {
+			if atomic.AddInt32(&active, 1) == 3 {
+				once.Do(func() { close(parallel) })
+			}
+			defer atomic.AddInt32(&active, -1)
+
+			select {
+			case <-parallel:
+			case <-time.After(5 * time.Second):
+			}
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer subscription.Close()
+
+		pub, err := bps.NewPublisher(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		for _, data := range []string{"v1", "v2", "v3"} {
+			Expect(pub.Topic("topic").Publish(ctx, &bps.PubMessage{Data: []byte(data)})).To(Succeed())
+		}
+		Expect(pub.Close()).To(Succeed())
+
+		Eventually(parallel, 3*time.Second).Should(BeClosed())
+	})
+
+	It("should configure publish settings", func() {
+		url := "pubsub://" + projectID + "?emulator_host=" + srv.Addr
+
+		admin, err := bps.NewAdmin(ctx, url)
+		Expect(err).NotTo(HaveOccurred())
+		defer admin.Close()
+		Expect(admin.CreateTopic(ctx, "topic", nil)).To(Succeed())
+
+		pub, err := bps.NewPublisher(ctx, url+"&count_threshold=1&message_ordering=true&limit_exceeded_behavior=block")
+		Expect(err).NotTo(HaveOccurred())
+		defer pub.Close()
+
+		topic := pub.Topic("topic").(*pubsub.PubTopic)
+		Expect(topic.Topic().EnableMessageOrdering).To(BeTrue())
+		Expect(topic.Topic().PublishSettings.CountThreshold).To(Equal(1))
+		Expect(topic.Topic().PublishSettings.FlowControlSettings.LimitExceededBehavior).To(Equal(native.FlowControlBlock))
+
+		Expect(topic.Publish(ctx, &bps.PubMessage{ID: "k1", Data: []byte("v1")})).To(Succeed())
+		Expect(topic.Publish(ctx, &bps.PubMessage{ID: "k1", Data: []byte("v2")})).To(Succeed())
+		Expect(topic.Flush(ctx)).To(Succeed())
+		Expect(srv.Messages()).To(HaveLen(2))
+		Expect(srv.Messages()[0].OrderingKey).To(Equal("k1"))
+	})
+
+	It("should report all publish errors", func() {
+		pub, err := bps.NewPublisher(ctx, "pubsub://"+projectID+"?emulator_host="+srv.Addr)
+		Expect(err).NotTo(HaveOccurred())
+
+		topic := pub.Topic("missing")
+		Expect(topic.Publish(ctx, &bps.PubMessage{Data: []byte("v1")})).To(Succeed())
+		Expect(topic.Publish(ctx, &bps.PubMessage{Data: []byte("v2")})).To(Succeed())
+
+		err = pub.(*pubsub.Publisher).Flush(ctx)
+		Expect(err).To(BeAssignableToTypeOf(&pubsub.PublishError{}))
+		Expect(err.(*pubsub.PublishError).Errors).To(HaveLen(2))
+		Expect(pub.(*pubsub.Publisher).Flush(ctx)).To(Succeed())
+
+		Expect(topic.Publish(ctx, &bps.PubMessage{Data: []byte("v3")})).To(Succeed())
+		err = pub.Close()
+		Expect(err).To(BeAssignableToTypeOf(&pubsub.PublishError{}))
+		Expect(err.(*pubsub.PublishError).Errors).To(HaveLen(1))
+	})
+
+	It("should fail on invalid options", func() {
+		_, err := bps.NewPublisher(ctx, "pubsub://"+projectID+"?emulator_host="+srv.Addr+"&endpoint=pubsub.googleapis.com:443")
+		Expect(err).To(MatchError("emulator_host cannot be combined with credentials_file or endpoint"))
+
+		_, err = bps.NewSubscriber(ctx, "pubsub://"+projectID+"?emulator=localhost")
+		Expect(err).To(MatchError(`invalid URL query: unknown parameter "emulator"`))
+
+		_, err = bps.NewSubscriber(ctx, "pubsub://"+projectID+"?count_threshold=1")
+		Expect(err).To(MatchError(`invalid URL query: unknown parameter "count_threshold"`))
+
+		_, err = bps.NewPublisher(ctx, "pubsub://"+projectID+"?synchronous=true")
+		Expect(err).To(MatchError(`invalid URL query: unknown parameter "synchronous"`))
+	})
+
+	lint.QueryDoc("pubsub.go", pubsub.QueryParams...)
+})
+
 // ------------------------------------------------------------------------
 
 const projectID = "bsm-tech"
 
 func TestSuite(t *testing.T) {
+	// specs, that need no running emulator, are run without BPS_TEST too:
 	if !strings.Contains(os.Getenv("BPS_TEST"), "pubsub") {
-		t.Skipf("skipping test")
-		return
+		config.GinkgoConfig.FocusStrings = []string{`URL options`}
 	}
 
 	RegisterFailHandler(Fail)
@@ -0,0 +1,196 @@
+package bps
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryParams declares URL query parameters, supported by an implementation.
+// It is similar to flag.FlagSet: each parameter is bound to a destination,
+// values are parsed and validated by Parse.
+// The same declaration can be used to generate parameter documentation with Doc.
+//
+// It is meant to be used by pubsub implementations like this:
+//
+//   var params bps.QueryParams
+//   params.Duration(&config.Timeout, "timeout", "Operation timeout (default 10s).")
+//   params.Int(&config.BufferSize, "buffer.size", "Number of messages to buffer (default 256).")
+//   if err := params.Parse(u.Query()); err != nil {
+//     return nil, err
+//   }
+//
+// The zero value is ready to use.
+type QueryParams struct {
+	params []*queryParam
+}
+
+type queryParam struct {
+	name   string
+	usage  string
+	values []string // valid enum values, if any
+	set    func(string) error
+}
+
+// String declares a string parameter.
+func (p *QueryParams) String(dst *string, name, usage string) {
+	p.Func(name, usage, func(v string) error {
+		*dst = v
+		return nil
+	})
+}
+
+// Int declares an integer parameter.
+func (p *QueryParams) Int(dst *int, name, usage string) {
+	p.Func(name, usage, func(v string) error {
+		num, err := strconv.Atoi(v)
+		if err != nil {
+			return errInvalidSyntax("an integer")
+		}
+		*dst = num
+		return nil
+	})
+}
+
+// Bool declares a boolean parameter.
+// It accepts the values, accepted by strconv.ParseBool.
+func (p *QueryParams) Bool(dst *bool, name, usage string) {
+	p.Func(name, usage, func(v string) error {
+		ok, err := strconv.ParseBool(v)
+		if err != nil {
+			return errInvalidSyntax("a boolean")
+		}
+		*dst = ok
+		return nil
+	})
+}
+
+// Duration declares a time.Duration parameter.
+// It accepts the values, accepted by time.ParseDuration.
+func (p *QueryParams) Duration(dst *time.Duration, name, usage string) {
+	p.Func(name, usage, func(v string) error {
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return errInvalidSyntax("a duration")
+		}
+		*dst = dur
+		return nil
+	})
+}
+
+// Enum declares a parameter, which accepts only the given values.
+// Func fn is called with a validated value.
+func (p *QueryParams) Enum(name, usage string, values []string, fn func(string)) {
+	p.add(&queryParam{
+		name:   name,
+		usage:  usage,
+		values: values,
+		set: func(v string) error {
+			for _, s := range values {
+				if s == v {
+					fn(v)
+					return nil
+				}
+			}
+			return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+		},
+	})
+}
+
+// Func declares a parameter with a custom parser.
+// Func fn should return an error if value is invalid.
+func (p *QueryParams) Func(name, usage string, fn func(string) error) {
+	p.add(&queryParam{
+		name:  name,
+		usage: usage,
+		set:   fn,
+	})
+}
+
+func (p *QueryParams) add(param *queryParam) {
+	if p.lookup(param.name) != nil {
+		panic("query parameter " + param.name + " already declared")
+	}
+	p.params = append(p.params, param)
+}
+
+func (p *QueryParams) lookup(name string) *queryParam {
+	for _, param := range p.params {
+		if param.name == name {
+			return param
+		}
+	}
+	return nil
+}
+
+// Parse parses and applies query values.
+// It returns a *QueryError, listing every invalid or unknown parameter.
+func (p *QueryParams) Parse(query url.Values) error {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		param := p.lookup(name)
+		if param == nil {
+			errs = append(errs, fmt.Errorf("unknown parameter %q", name))
+			continue
+		}
+
+		v := query.Get(name)
+		if err := param.set(v); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s value %q: %w", name, v, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return &QueryError{Errors: errs}
+	}
+	return nil
+}
+
+// Doc returns parameter documentation in a format, suitable for package docs:
+//
+//   name
+//     usage
+//
+func (p *QueryParams) Doc() string {
+	var b strings.Builder
+	for _, param := range p.params {
+		b.WriteString("  ")
+		b.WriteString(param.name)
+		b.WriteByte('\n')
+		for _, line := range strings.Split(param.usage, "\n") {
+			b.WriteString("    ")
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// QueryError is returned when URL query contains invalid or unknown parameters.
+type QueryError struct {
+	Errors []error
+}
+
+// Error implements error interface.
+func (e *QueryError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid URL query: " + strings.Join(msgs, "; ")
+}
+
+type errInvalidSyntax string
+
+func (e errInvalidSyntax) Error() string {
+	return "not " + string(e)
+}
@@ -0,0 +1,93 @@
+package bps_test
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/bsm/bps"
+
+	. "github.com/bsm/ginkgo"
+	. "github.com/bsm/gomega"
+)
+
+var _ = Describe("QueryParams", func() {
+	var subject *bps.QueryParams
+	var config struct {
+		Name    string
+		Size    int
+		Enabled bool
+		Timeout time.Duration
+		Mode    string
+	}
+
+	BeforeEach(func() {
+		config.Name, config.Size, config.Enabled, config.Timeout, config.Mode = "", 0, false, 0, ""
+
+		subject = new(bps.QueryParams)
+		subject.String(&config.Name, "name", "The name.")
+		subject.Int(&config.Size, "size", "The size.")
+		subject.Bool(&config.Enabled, "enabled", "Whether enabled.")
+		subject.Duration(&config.Timeout, "timeout", "The timeout.\nMay span multiple lines.")
+		subject.Enum("mode", "The mode.", []string{"fast", "slow"}, func(v string) { config.Mode = v })
+	})
+
+	It("should panic on duplicate declarations", func() {
+		Expect(func() {
+			subject.String(&config.Name, "name", "Again.")
+		}).To(Panic())
+	})
+
+	It("should parse", func() {
+		Expect(subject.Parse(url.Values{
+			"name":    {"foo"},
+			"size":    {"8"},
+			"enabled": {"true"},
+			"timeout": {"3s"},
+			"mode":    {"slow"},
+		})).To(Succeed())
+		Expect(config.Name).To(Equal("foo"))
+		Expect(config.Size).To(Equal(8))
+		Expect(config.Enabled).To(BeTrue())
+		Expect(config.Timeout).To(Equal(3 * time.Second))
+		Expect(config.Mode).To(Equal("slow"))
+	})
+
+	It("should leave missing values untouched", func() {
+		config.Size = 5
+		Expect(subject.Parse(url.Values{})).To(Succeed())
+		Expect(config.Size).To(Equal(5))
+	})
+
+	It("should report all invalid and unknown parameters", func() {
+		err := subject.Parse(url.Values{
+			"size":    {"x"},
+			"enabled": {"maybe"},
+			"timeout": {"1"},
+			"mode":    {"medium"},
+			"nmae":    {"foo"},
+		})
+		Expect(err).To(BeAssignableToTypeOf(&bps.QueryError{}))
+		Expect(err.(*bps.QueryError).Errors).To(HaveLen(5))
+		Expect(err).To(MatchError(`invalid URL query: ` +
+			`invalid enabled value "maybe": not a boolean; ` +
+			`invalid mode value "medium": must be one of fast, slow; ` +
+			`unknown parameter "nmae"; ` +
+			`invalid size value "x": not an integer; ` +
+			`invalid timeout value "1": not a duration`))
+	})
+
+	It("should generate docs", func() {
+		Expect(subject.Doc()).To(Equal(`  name
+    The name.
+  size
+    The size.
+  enabled
+    Whether enabled.
+  timeout
+    The timeout.
+    May span multiple lines.
+  mode
+    The mode.
+`))
+	})
+})
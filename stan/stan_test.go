@@ -6,16 +6,24 @@ package stan_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bsm/bps"
 	"github.com/bsm/bps/internal/lint"
+	bpsstan "github.com/bsm/bps/stan"
+	"github.com/nats-io/nats-streaming-server/server"
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/stan.go"
 
 	. "github.com/bsm/ginkgo"
+	"github.com/bsm/ginkgo/config"
 	. "github.com/bsm/gomega"
 )
 
@@ -37,6 +45,17 @@ var _ = Describe("Publisher", func() {
 		Expect(subject).NotTo(BeNil())
 	})
 
+	It("should init from URL with connection options", func() {
+		pub, err := bps.NewPublisher(ctx, fmt.Sprintf("stan://%s,%s/%s?client_id=%s&max_reconnects=-1&reconnect_wait=100ms", stanAddrs, stanAddrs, clusterID, bps.GenClientID()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Close()).To(Succeed())
+	})
+
+	It("should fail on invalid connection options", func() {
+		_, err := bps.NewPublisher(ctx, fmt.Sprintf("stan://%s/%s?reconnect_wait=bad&durable_name=x", stanAddrs, clusterID))
+		Expect(err).To(MatchError(`invalid URL query: unknown parameter "durable_name"; invalid reconnect_wait value "bad": not a duration`))
+	})
+
 	Context("lint", func() {
 		var shared lint.PublisherInput
 
@@ -87,6 +106,132 @@ var _ = Describe("Subscriber", func() {
 	})
 })
 
+var _ = Describe("Subscriber (manual ack)", func() {
+	var subject bps.Subscriber
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		var err error
+		subject, err = bps.NewSubscriber(ctx, fmt.Sprintf("stan://%s/%s?client_id=%s&manual_ack=true&ack_wait=1s&max_inflight=1", stanAddrs, clusterID, bps.GenClientID()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+	})
+
+	It("should fail on invalid subscription options", func() {
+		_, err := bps.NewSubscriber(ctx, fmt.Sprintf("stan://%s/%s?ack_wait=bad&manual_ack=1", stanAddrs, clusterID))
+		Expect(err).To(MatchError(`invalid URL query: invalid ack_wait value "bad": not a duration`))
+	})
+
+	Context("lint", func() {
+		var shared lint.SubscriberInput
+
+		BeforeEach(func() {
+			shared = lint.SubscriberInput{
+				Subject: subject,
+				Seed: func(topic string, messages []bps.SubMessage) {
+					Expect(seedMessages(topic, messages)).To(Succeed())
+				},
+			}
+		})
+
+		lint.SubscriberPositionNewest(&shared)
+		lint.SubscriberPositionOldest(&shared)
+	})
+})
+
+var _ = Describe("Subscriber (events)", func() {
+	var srv *server.StanServer
+	var port int
+	var nc *nats.Conn
+	var subject bps.Subscriber
+
+	BeforeEach(func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		port = l.Addr().(*net.TCPAddr).Port
+		Expect(l.Close()).To(Succeed())
+
+		srv = runServer(port)
+
+		// user-provided NATS connections are shared across STAN reconnects:
+		nc, err = nats.Connect(fmt.Sprintf("nats://127.0.0.1:%d", port), nats.ReconnectWait(10*time.Millisecond), nats.MaxReconnects(-1))
+		Expect(err).NotTo(HaveOccurred())
+
+		subject, err = bpsstan.NewSubscriber(clusterID, bps.GenClientID(), "", "", []stan.Option{stan.NatsConn(nc), stan.Pings(1, 3)})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+		nc.Close()
+		srv.Shutdown()
+	})
+
+	It("should report each event once", func() {
+		var events []bps.SubEventType
+		var mu sync.Mutex
+
+		sub, err := subject.Topic("bps-unittest-events").Subscribe(
+			bps.HandlerFunc(func(bps.SubMessage) {}),
+			bps.IgnoreSubscriptionErrors(),
+			bps.WithEventHandler(func(e bps.SubEvent) {
+				mu.Lock()
+				events = append(events, e.Type)
+				mu.Unlock()
+			}),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := func() []bps.SubEventType {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]bps.SubEventType{}, events...)
+		}
+
+		// NATS connection and STAN session are both lost and re-established on every restart:
+		expected := []bps.SubEventType{bps.EventDisconnected, bps.EventReconnected, bps.EventDisconnected, bps.EventReconnected}
+		for i := 1; i <= 2; i++ {
+			srv.Shutdown()
+			srv = runServer(port)
+
+			Eventually(received, 10*time.Second).Should(HaveLen(len(expected) * i))
+		}
+		Consistently(received, time.Second).Should(Equal(append(expected, expected...)))
+	})
+
+	It("should stop reconnecting once the NATS connection is closed", func() {
+		var errs []error
+		var mu sync.Mutex
+
+		sub, err := subject.Topic("bps-unittest-events").Subscribe(
+			bps.HandlerFunc(func(bps.SubMessage) {}),
+			bps.WithErrorHandler(func(err error) {
+				if errors.Is(err, nats.ErrConnectionClosed) {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		defer sub.Close()
+
+		received := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(errs)
+		}
+
+		nc.Close()
+		Eventually(received, 10*time.Second).Should(Equal(1))
+		Consistently(received, 3*time.Second).Should(Equal(1))
+	})
+})
+
 // Not a proper Queue Subscriptions test, but smth to make sure subscriber is not broken
 var _ = Describe("Subscriber (Queue)", func() {
 	var subject bps.Subscriber
@@ -126,18 +271,34 @@ var _ = Describe("Subscriber (Queue)", func() {
 	})
 })
 
+var _ = Describe("URL options", func() {
+	lint.QueryDoc("stan.go", bpsstan.QueryParams...)
+})
+
 // ----------------------------------------------------------------------------
 
 func TestSuite(t *testing.T) {
+	// specs, that need no running server, are run without BPS_TEST too:
 	if !strings.Contains(os.Getenv("BPS_TEST"), "stan") {
-		t.Skipf("skipping test")
-		return
+		config.GinkgoConfig.FocusStrings = []string{`URL options`}
 	}
 
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "bps/stan")
 }
 
+func runServer(port int) *server.StanServer {
+	opts := server.GetDefaultOptions()
+	opts.ID = clusterID
+
+	natsOpts := server.DefaultNatsServerOptions
+	natsOpts.Port = port
+
+	srv, err := server.RunServerWithOpts(opts, &natsOpts)
+	Expect(err).NotTo(HaveOccurred())
+	return srv
+}
+
 func readMessages(topic string, count int) ([]*bps.PubMessage, error) {
 	conn, err := stan.Connect(clusterID, bps.GenClientID(), stan.NatsURL("nats://"+stanAddrs))
 	if err != nil {
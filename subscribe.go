@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/bsm/bps/internal/concurrent"
 )
@@ -48,6 +49,9 @@ func (f HandlerFunc) Handle(msg SubMessage) {
 	f(msg)
 }
 
+// HandlerFactory creates a handler for an individual partition.
+type HandlerFactory func(partition int) Handler
+
 // SafeHandler wraps a handler with a mutex to synchronize access.
 // It is intended to be used only by subscriber implementations which need it.
 // It shouldn't be used by lib consumer.
@@ -86,6 +90,47 @@ const (
 	PositionOldest StartPosition = "oldest"
 )
 
+// SubEventType defines a subscription lifecycle event type.
+type SubEventType string
+
+// SubEventType options.
+const (
+	// EventDisconnected is emitted when subscriber loses connection to the server.
+	EventDisconnected SubEventType = "disconnected"
+
+	// EventReconnected is emitted when subscriber re-establishes connection to the server
+	// (and re-subscribes, if needed).
+	EventReconnected SubEventType = "reconnected"
+
+	// EventPartitionsChanged is emitted when subscription starts consuming partitions,
+	// that were added after subscribing.
+	EventPartitionsChanged SubEventType = "partitions_changed"
+)
+
+// SubEvent is a subscription lifecycle event.
+type SubEvent struct {
+	// Type is the event type.
+	Type SubEventType
+	// Detail is an optional human-readable event detail.
+	Detail string
+	// Err is an optional event cause.
+	Err error
+}
+
+// String returns a human-readable event description.
+func (e SubEvent) String() string {
+	s := string(e.Type)
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// ----------------------------------------------------------------------------
+
 // SubOptions holds subscription options.
 type SubOptions struct {
 	// StartAt defines starting position to consume messages.
@@ -95,6 +140,32 @@ type SubOptions struct {
 	// ErrorHandler is a subscription error handler (system/implementation-specific errors).
 	// Default: log errors to STDERR.
 	ErrorHandler func(error)
+	// EventHandler is a subscription lifecycle event handler (disconnects, reconnects etc).
+	// May not be supported by some implementations.
+	// Default: ignore events.
+	EventHandler func(SubEvent)
+	// ManualAck makes subscription acknowledge messages only after they are handled,
+	// so messages, that were not handled (e.g. because of a crash), are re-delivered.
+	// May not be supported by some implementations.
+	// Default: implementation-specific.
+	ManualAck bool
+	// AckWait defines how long to wait for a message acknowledgement before re-delivering it.
+	// May not be supported by some implementations.
+	// Default: implementation-specific.
+	AckWait time.Duration
+	// MaxInflight limits the number of delivered, but not yet acknowledged messages.
+	// May not be supported by some implementations.
+	// Default: implementation-specific.
+	MaxInflight int
+	// MaxInflightBytes limits the total size of delivered, but not yet acknowledged messages.
+	// May not be supported by some implementations.
+	// Default: implementation-specific.
+	MaxInflightBytes int
+	// HandlerFactory creates an individual handler for each partition, so partitions are handled
+	// in parallel, while the order of messages within a partition is preserved.
+	// Implementations, that don't support it, use the handler, passed to Subscribe.
+	// Default: all partitions are handled (sequentially) by the handler, passed to Subscribe.
+	HandlerFactory HandlerFactory
 }
 
 // Apply configures SubOptions struct by applying each single SubOption one by one.
@@ -118,6 +189,9 @@ func (o *SubOptions) Apply(options []SubOption) *SubOptions {
 		log := log.New(os.Stderr, "[bps] ", log.LstdFlags) // TODO: maybe make global?..
 		o.ErrorHandler = func(err error) { log.Println(err) }
 	}
+	if o.EventHandler == nil {
+		o.EventHandler = func(SubEvent) {} // noop
+	}
 
 	for _, opt := range options {
 		opt(o)
@@ -135,6 +209,41 @@ func StartAt(pos StartPosition) SubOption {
 	}
 }
 
+// WithManualAck configures subscription to acknowledge messages only after they are handled.
+func WithManualAck() SubOption {
+	return func(o *SubOptions) {
+		o.ManualAck = true
+	}
+}
+
+// WithAckWait configures subscription acknowledgement timeout.
+func WithAckWait(d time.Duration) SubOption {
+	return func(o *SubOptions) {
+		o.AckWait = d
+	}
+}
+
+// WithMaxInflight configures the max number of delivered, but not yet acknowledged messages.
+func WithMaxInflight(n int) SubOption {
+	return func(o *SubOptions) {
+		o.MaxInflight = n
+	}
+}
+
+// WithMaxInflightBytes configures the max total size of delivered, but not yet acknowledged messages.
+func WithMaxInflightBytes(n int) SubOption {
+	return func(o *SubOptions) {
+		o.MaxInflightBytes = n
+	}
+}
+
+// WithHandlerFactory configures subscription to handle each partition with its own handler.
+func WithHandlerFactory(f HandlerFactory) SubOption {
+	return func(o *SubOptions) {
+		o.HandlerFactory = f
+	}
+}
+
 // WithErrorHandler configures subscription error handler.
 func WithErrorHandler(h func(error)) SubOption {
 	return func(o *SubOptions) {
@@ -142,6 +251,13 @@ func WithErrorHandler(h func(error)) SubOption {
 	}
 }
 
+// WithEventHandler configures subscription lifecycle event handler.
+func WithEventHandler(h func(SubEvent)) SubOption {
+	return func(o *SubOptions) {
+		o.EventHandler = h
+	}
+}
+
 // IgnoreSubscriptionErrors configures subscription to silently ignore errors.
 func IgnoreSubscriptionErrors() SubOption {
 	return func(o *SubOptions) {
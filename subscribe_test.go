@@ -2,6 +2,7 @@ package bps_test
 
 import (
 	"context"
+	"io"
 	"net/url"
 
 	"github.com/bsm/bps"
@@ -62,4 +63,54 @@ var _ = Describe("InMemSubscriber", func() {
 
 		lint.SubscriberPositionOldest(&shared)
 	})
+
+	Context("admin lint", func() {
+		var shared lint.AdminInput
+
+		BeforeEach(func() {
+			shared = lint.AdminInput{Subject: bps.NewInMemSubscriber(nil)}
+		})
+
+		lint.Admin(&shared)
+	})
+})
+
+var _ = Describe("SubOptions", func() {
+	It("should apply defaults", func() {
+		opts := (&bps.SubOptions{}).Apply(nil)
+		Expect(opts.ErrorHandler).NotTo(BeNil())
+		Expect(opts.EventHandler).NotTo(BeNil())
+	})
+
+	It("should apply options", func() {
+		var events []bps.SubEvent
+		opts := (&bps.SubOptions{}).Apply([]bps.SubOption{
+			bps.StartAt(bps.PositionOldest),
+			bps.WithEventHandler(func(e bps.SubEvent) { events = append(events, e) }),
+		})
+		Expect(opts.StartAt).To(Equal(bps.PositionOldest))
+
+		opts.EventHandler(bps.SubEvent{Type: bps.EventReconnected})
+		Expect(events).To(ConsistOf(bps.SubEvent{Type: bps.EventReconnected}))
+	})
+
+	It("should apply handler factory", func() {
+		var partitions []int
+		opts := (&bps.SubOptions{}).Apply([]bps.SubOption{
+			bps.WithHandlerFactory(func(partition int) bps.Handler {
+				partitions = append(partitions, partition)
+				return bps.HandlerFunc(func(bps.SubMessage) {})
+			}),
+		})
+		Expect(opts.HandlerFactory(3)).NotTo(BeNil())
+		Expect(partitions).To(Equal([]int{3}))
+	})
+})
+
+var _ = Describe("SubEvent", func() {
+	It("should format", func() {
+		Expect(bps.SubEvent{Type: bps.EventReconnected}.String()).To(Equal("reconnected"))
+		Expect(bps.SubEvent{Type: bps.EventDisconnected, Err: io.EOF}.String()).To(Equal("disconnected: EOF"))
+		Expect(bps.SubEvent{Type: bps.EventPartitionsChanged, Detail: "topic/3"}.String()).To(Equal("partitions_changed: topic/3"))
+	})
 })